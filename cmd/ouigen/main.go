@@ -0,0 +1,109 @@
+// ouigen regenera el registro de fabricantes embebido (internal/utils/data/oui.tsv).
+// Sin argumentos descarga los tres registros del IEEE (MA-L, MA-M y MA-S); es lo
+// que ejecuta "go generate ./internal/utils". También acepta archivos o URLs:
+//
+//	go run ./cmd/ouigen -o internal/utils/data/oui.tsv oui.csv mam.csv oas.csv
+//
+// Acepta los CSV o el oui.txt del IEEE y también TSV en el formato embebido.
+package main
+
+import (
+	scan "escaner/internal/utils"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ieeeRegistries registros públicos del IEEE: MA-L, MA-M y MA-S
+var ieeeRegistries = []string{
+	"https://standards-oui.ieee.org/oui/oui.csv",
+	"https://standards-oui.ieee.org/oui28/mam.csv",
+	"https://standards-oui.ieee.org/oui36/oas.csv",
+}
+
+const header = `# Registro IEEE de fabricantes (MA-L / MA-M / MA-S): prefijo hexadecimal <TAB> organización.
+# El largo del prefijo indica el bloque: 6 dígitos = MA-L (/24), 7 = MA-M (/28), 9 = MA-S (/36).
+# Generado con "go generate ./internal/utils" (cmd/ouigen); no editar a mano.
+`
+
+func main() {
+	out := flag.String("o", "", "Archivo de salida (por defecto stdout)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "uso: ouigen [-o oui.tsv] [oui.csv mam.csv oas.csv ... | URL ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	sources := flag.Args()
+	if len(sources) == 0 {
+		sources = ieeeRegistries
+	}
+
+	db := map[string]string{}
+	for _, path := range sources {
+		r, err := openSource(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		entries, err := scan.ParseOUIRegistry(r)
+		r.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error en %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		for k, v := range entries {
+			db[k] = v
+		}
+	}
+	if len(db) == 0 {
+		fmt.Fprintln(os.Stderr, "error: ningún prefijo OUI en los archivos de entrada")
+		os.Exit(1)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+	if _, err := io.WriteString(w, header); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	if err := scan.WriteOUIRegistry(w, db); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "%d prefijos OUI escritos\n", len(db))
+}
+
+// openSource abre un archivo local o descarga una URL http(s)
+func openSource(path string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		return os.Open(path)
+	}
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	// el servidor del IEEE rechaza clientes sin User-Agent de navegador
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; ouigen)")
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("descargando %s: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("descargando %s: %s", path, resp.Status)
+	}
+	return resp.Body, nil
+}
@@ -2,24 +2,58 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"escaner/internal/backend"
+	"escaner/internal/export"
+	"escaner/internal/history"
 	"escaner/internal/models"
 	scan "escaner/internal/utils"
 	"escaner/internal/wsclient"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"sync/atomic"
 	"time"
 )
 
 var (
-	timeoutMs   = flag.Int("timeout", 1000, "Timeout en ms para ping / tcp connect")
-	portsArg    = flag.String("ports", "22,80,443,3389,445,139,9100,631,515,3306,53,8080,137,161", "Puertos separados por comas para fallback y fingerprint")
-	concurrency = flag.Int("c", 200, "Concurrencia máxima para escaneo")
-	jsonOut     = flag.Bool("json", false, "Salida JSON en vez de texto")
+	profileName  = flag.String("profile", scan.DefaultProfile, "Perfil de escaneo (quick, standard, deep, inventory o uno propio); los flags indicados explícitamente lo ajustan")
+	profilesFile = flag.String("profiles", "", "Archivo JSON de perfiles de escaneo propio (reemplaza/agrega a los embebidos)")
+	retries      = flag.Int("retries", 0, "Reintentos del ping, de las conexiones TCP y de las sondas UDP sin respuesta")
+	adaptive     = flag.Bool("adaptive", true, "Ajustar los timeouts de cada host a su RTT medido (-timeout queda como valor inicial)")
+	maxTimeoutMs = flag.Int("max-timeout", 0, "Tope en ms de los timeouts adaptativos (0 = 4 veces -timeout)")
+	timeoutMs    = flag.Int("timeout", 1000, "Timeout en ms para ping / tcp connect")
+	portsArg     = flag.String("ports", "tcp:22,80,443,3389,445,139,9100,631,515,3306,53,8080 udp:53,123", "Puertos separados por comas (o rangos como 1-1024) para fallback y fingerprint; tcp:/udp: eligen el protocolo")
+	allPorts     = flag.Bool("all-ports", false, "Reportar todos los puertos abiertos de cada host en vez de parar en el primero")
+	concurrency  = flag.Int("c", 200, "Concurrencia máxima para escaneo")
+	rate         = flag.Int("rate", 0, "Máximo de sondas por segundo en todo el escaneo (0 = sin límite)")
+	subnetRate   = flag.Int("subnet-rate", 0, "Máximo de sondas por segundo hacia una misma /24 (0 = sin límite)")
+	jsonOut      = flag.Bool("json", false, "Salida JSON en vez de texto")
+	services     = flag.Bool("services", false, "Identificar servicio y versión en cada puerto abierto")
+	probesFile   = flag.String("service-probes", "", "Archivo JSON de sondas de servicio propio (reemplaza/agrega a las embebidas)")
+	rulesFile    = flag.String("rules", "", "Archivo JSON de reglas de clasificación de dispositivos (reemplaza/agrega a las embebidas)")
+	ouiFile      = flag.String("oui", "", "Registro OUI local (CSV/TXT del IEEE) que actualiza el embebido")
+	excludeArg   = flag.String("exclude", "", "Objetivos a excluir (CIDR, rangos o IPs separados por comas)")
+	netBcast     = flag.Bool("netbcast", false, "Incluir las direcciones de red y broadcast de los CIDR IPv4")
+	mdnsFlag     = flag.Bool("mdns", true, "Consultar por mDNS/DNS-SD nombres, modelos y servicios anunciados en la red local")
+	netbios      = flag.Bool("netbios", true, "Consultar el nombre NetBIOS (NBSTAT por UDP 137) de cada host vivo")
+	httpFlag     = flag.Bool("http", true, "Tomar la huella de cada puerto web abierto (HTTP/HTTPS): título, Server, realm, generator, favicon")
+	tlsFlag      = flag.Bool("tls", true, "Inspeccionar el certificado de los puertos TLS abiertos (sujeto, SANs, emisor, vencimiento)")
+	rtspFlag     = flag.Bool("rtsp", true, "Enviar OPTIONS/DESCRIBE a los puertos RTSP abiertos (554, 8554) para confirmar cámaras/NVR y leer fabricante y modelo")
+	sshFlag      = flag.Bool("ssh", true, "Interpretar el banner de los puertos SSH abiertos: sistema, distribución, release y familia de equipo")
+	osFlag       = flag.Bool("os", true, "Deducir la familia de sistema (Windows, Linux, BSD/macOS, embebido, red) del TTL y del SYN-ACK")
+	ssdpFlag     = flag.Bool("ssdp", true, "Buscar equipos UPnP por SSDP (M-SEARCH) y leer su descripción (nombre, fabricante, modelo, serie)")
+	snmpFlag     = flag.Bool("snmp", true, "Consultar sysDescr/sysObjectID/sysName por SNMP (UDP 161) de cada host vivo")
+	snmpComm     = flag.String("snmp-community", "public", "Comunidades SNMP v1/v2c separadas por comas")
+	ndp          = flag.Bool("ndp", false, "Descubrir vecinos IPv6 del segmento (caché NDP + ping a ff02::1) y agregarlos a los objetivos")
+	historyDir   = flag.String("history", history.DefaultDir(), "Directorio del historial local de escaneos (vacío = no guardar ni comparar)")
+	historyKeep  = flag.Int("history-keep", history.DefaultKeep, "Escaneos que se conservan por rango en el historial (0 = todos)")
+	diffOnly     = flag.Bool("diff", false, "No escanear: mostrar los cambios entre los dos últimos escaneos guardados de los objetivos")
+	activeARP    = flag.Bool("arp", true, "Enviar ARP requests propios si la IP no está en la tabla de vecinos (Linux, requiere CAP_NET_RAW)")
 
 	// Config backend
 	//ipServer = flag.String("ipserver", "192.168.0.24", "direcion del servidor del backend")
@@ -32,9 +66,83 @@ var (
 )
 
 func main() {
+	var snmpV3 []scan.SNMPv3User
+	flag.Func("snmp-v3", "Usuario SNMPv3 usuario[:auth:clave[:priv:clave]] (auth MD5/SHA/SHA256..., priv DES/AES); repetible", func(s string) error {
+		u, err := scan.ParseSNMPv3User(s)
+		if err == nil {
+			snmpV3 = append(snmpV3, u)
+		}
+		return err
+	})
+
+	// los archivos de -o se crean recién antes de escanear, cuando ya se validó todo lo demás
+	var outputs []string
+	flag.Func("o", "Salida adicional formato:ruta ("+strings.Join(export.Formats(), ", ")+"; ruta - = salida estándar, y los mensajes van a stderr); repetible", func(s string) error {
+		if err := export.Check(s); err != nil {
+			return err
+		}
+		outputs = append(outputs, s)
+		return nil
+	})
 
 	flag.Parse()
+	// si una salida -o va a stdout, los mensajes para el usuario pasan a stderr
+	// para no mezclarse con el CSV/NDJSON/XML
+	var logOut io.Writer = os.Stdout
+	for _, spec := range outputs {
+		if export.ToStdout(spec) {
+			if *jsonOut {
+				fmt.Fprintf(os.Stderr, "-json y -o %s escriben ambos en la salida estándar\n", spec)
+				os.Exit(1)
+			}
+			logOut = os.Stderr
+		}
+	}
+	backend.Log = logOut
+	scan.ActiveARP = *activeARP
+	if *probesFile != "" {
+		if err := scan.LoadServiceProbes(*probesFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error cargando sondas: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *ouiFile != "" {
+		if err := scan.LoadOUIDatabase(*ouiFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error cargando registro OUI: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *rulesFile != "" {
+		if err := scan.LoadDeviceRules(*rulesFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error cargando reglas: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *profilesFile != "" {
+		if err := scan.LoadScanProfiles(*profilesFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error cargando perfiles: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	profile, err := scan.LookupProfile(*profileName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	var store *history.Store
+	if *historyDir != "" {
+		if store, err = history.Open(*historyDir); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		store.Keep = *historyKeep
+	}
+	if *diffOnly {
+		os.Exit(showDiff(store))
+	}
+	wsclient.History = store
 	backendURL := fmt.Sprintf("http://%s:3000/dispositivos/found", *ipServer)
+	changesURL := fmt.Sprintf("http://%s:3000/dispositivos/changes", *ipServer)
 	wsURL := fmt.Sprintf("%s:8082", *ipServer)
 	ip := fmt.Sprint("", *ipServer)
 	isFallback := true
@@ -45,9 +153,9 @@ func main() {
 		for {
 			err := backend.EnviarEquipo(equipo, backendURLEquipos, time.Duration(*backendTimeoutSec)*time.Second)
 			if err != nil {
-				fmt.Println("Error enviando equipo:", err)
+				fmt.Fprintln(logOut, "Error enviando equipo:", err)
 			} else {
-				fmt.Println("✅ Datos del equipo enviados correctamente al backend")
+				fmt.Fprintln(logOut, "✅ Datos del equipo enviados correctamente al backend")
 			}
 			time.Sleep(24 * time.Hour) // o cada cierto tiempo que definas
 		}
@@ -56,7 +164,7 @@ func main() {
 	//----------------------------
 
 	// Si no hay argumento, arrancamos solo el servidor HTTP (modo agente)
-	if flag.NArg() < 1 {
+	if flag.NArg() < 1 && !*ndp {
 		// go httpserver.RunHTTPServer(
 		// 	*portsArg,
 		// 	*timeoutMs,
@@ -69,28 +177,60 @@ func main() {
 		// 🚀 Iniciar conexión WebSocket
 		//go wsclient.ConnectWebSocket("192.168.0.24:8082") // o la IP donde corre tu backend
 		//go wsclient.ConnectWebSocket("192.168.182.136:8082") // o la IP donde corre tu backend
-		go wsclient.ConnectWebSocket(wsURL, ip, isFallback)
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
 
-		fmt.Println("Servidor del agente escuchando en :8081 (modo servidor + WS).")
+		wsDone := make(chan struct{})
+		go func() {
+			defer close(wsDone)
+			wsclient.ConnectWebSocket(wsURL, ip, isFallback)
+		}()
+
+		fmt.Fprintln(logOut, "Servidor del agente escuchando en :8081 (modo servidor + WS).")
 		//select {}
 		// Esperar Ctrl+C
-		interrupt := make(chan os.Signal, 1)
-		signal.Notify(interrupt, os.Interrupt)
 		<-interrupt
 
-		fmt.Println("🔌 Señal recibida, cerrando proceso...")
-
+		fmt.Fprintln(logOut, "🔌 Señal recibida, cerrando proceso...")
+		// ConnectWebSocket también recibe la señal: cancela el escaneo en curso y
+		// envía el mensaje final antes de desconectarse
+		<-wsDone
+		return
 	}
 
 	// Si sí hay argumento, ejecutamos flujo CLI: escanear -> imprimir -> enviar
-	arg := flag.Arg(0)
-	ips, err := scan.ExpandArgToIPs(arg)
+	// Ctrl+C cancela el escaneo y deja imprimir lo que se alcanzó a escanear
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// objetivos: uno o más argumentos (CIDR, rangos, IPs), recorridos sin expandir en memoria
+	var excludes []string
+	if *excludeArg != "" {
+		excludes = append(excludes, *excludeArg)
+	}
+	targets, err := scan.ParseTargets(flag.Args(), excludes)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error procesando argumento: %v\n", err)
 		os.Exit(1)
 	}
-	ports := scan.ParsePorts(*portsArg)
-	timeout := time.Duration(*timeoutMs) * time.Millisecond
+	targets.KeepNetBcast = *netBcast
+	opts := profileOptions(profile, snmpV3)
+
+	if *ndp {
+		neighbors := scan.DiscoverIPv6Neighbors(ctx, 2*opts.Timeout)
+		fmt.Fprintf(logOut, "Vecinos IPv6 descubiertos: %d\n", len(neighbors))
+		for _, n := range neighbors {
+			_ = targets.Add(n)
+		}
+	}
+
+	var exports export.Set
+	for _, spec := range outputs {
+		if err := exports.Add(spec); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
 
 	var aliveCount int64 = 0
 
@@ -98,19 +238,25 @@ func main() {
 	onAlive := func(r models.Result) {
 		if r.Alive {
 			atomic.AddInt64(&aliveCount, 1)
-			fmt.Println("funcion del main")
-			fmt.Println("Dispositivooooooooooo vivo detectado:", scan.FormatResult(r))
+			if err := exports.Result(r); err != nil {
+				fmt.Fprintln(os.Stderr, "Error exportando:", err)
+			}
 			err := backend.SendToBackend(r, time.Duration(*backendTimeoutSec)*time.Second, backendURL)
 			if err != nil {
-				fmt.Println("Error enviando al backend:", err)
+				fmt.Fprintln(logOut, "Error enviando al backend:", err)
 			}
 		}
 	}
 
 	// Escaneo paralelo con callback para manejar resultados en vivo
-	results := scan.ScanIPs(ips, ports, timeout, *concurrency, onAlive)
+	started := time.Now()
+	results, down := scan.ScanIPs(ctx, targets.All(), opts, onAlive)
 
 	// Output CLI completo
+	meta := export.Meta{Args: os.Args[1:], Started: started, Finished: time.Now(), Down: down}
+	if err := exports.Close(meta, results); err != nil {
+		fmt.Fprintln(os.Stderr, "Error exportando:", err)
+	}
 
 	if *jsonOut {
 		enc := json.NewEncoder(os.Stdout)
@@ -118,9 +264,139 @@ func main() {
 		_ = enc.Encode(results)
 	} else {
 		for _, r := range results {
-			fmt.Println(scan.FormatResult(r))
+			fmt.Fprintln(logOut, scan.FormatResult(r))
+		}
+		fmt.Fprintf(logOut, "Hosts sin respuesta: %d\n", down)
+	}
+
+	if ctx.Err() != nil {
+		fmt.Fprintf(logOut, "Escaneo cancelado. Dispositivos vivos enviados: %d\n", aliveCount)
+		return
+	}
+
+	// un escaneo cancelado queda a medias: no se guarda para no inventar equipos desaparecidos
+	if store != nil {
+		run := history.NewRun(wsclient.GetMacAddress(), flag.Args(), excludes, started, results)
+		run.Profile = profile.Name
+		run.PortsEnumerated = opts.AllPorts || opts.ServiceDetection
+		changes, prevID, err := store.Record(run)
+		if err != nil {
+			fmt.Fprintln(logOut, "Error en el historial:", err)
+		}
+		if prevID != "" && !*jsonOut {
+			fmt.Fprintf(logOut, "Cambios respecto del escaneo %s: %d\n", prevID, len(changes))
+			for _, c := range changes {
+				fmt.Fprintln(logOut, history.FormatChange(c))
+			}
+		}
+		if len(changes) > 0 {
+			if err := backend.SendChanges(run, prevID, changes, time.Duration(*backendTimeoutSec)*time.Second, changesURL); err != nil {
+				fmt.Fprintln(logOut, "Error enviando cambios al backend:", err)
+			}
 		}
 	}
+	fmt.Fprintf(logOut, "Escaneo completado. Dispositivos vivos enviados: %d\n", aliveCount)
+}
 
-	fmt.Printf("Escaneo completado. Dispositivos vivos enviados: %d\n", aliveCount)
+// profileOptions parte del perfil y aplica encima solo los flags que se
+// indicaron en la línea de comandos
+func profileOptions(profile scan.ScanProfile, snmpV3 []scan.SNMPv3User) scan.ScanOptions {
+	opts := profile.Options()
+	snmpOn := opts.SNMP != nil
+	snmpCreds := len(snmpV3) > 0
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "ports":
+			opts.Ports, opts.UDPPorts = scan.ParsePortSpec(*portsArg)
+		case "timeout":
+			opts.Timeout = time.Duration(*timeoutMs) * time.Millisecond
+		case "c":
+			opts.Concurrency = *concurrency
+		case "retries":
+			opts.Retries = *retries
+		case "adaptive":
+			opts.AdaptiveTimeout = *adaptive
+		case "max-timeout":
+			opts.MaxTimeout = time.Duration(*maxTimeoutMs) * time.Millisecond
+		case "all-ports":
+			opts.AllPorts = *allPorts
+		case "services":
+			opts.ServiceDetection = *services
+		case "rate":
+			opts.Rate = *rate
+		case "subnet-rate":
+			opts.SubnetRate = *subnetRate
+		case "mdns":
+			opts.MDNS = *mdnsFlag
+		case "netbios":
+			opts.NetBIOS = *netbios
+		case "ssdp":
+			opts.SSDP = *ssdpFlag
+		case "tls":
+			opts.TLS = *tlsFlag
+		case "http":
+			opts.HTTP = *httpFlag
+		case "rtsp":
+			opts.RTSP = *rtspFlag
+		case "ssh":
+			opts.SSH = *sshFlag
+		case "os":
+			opts.OSFingerprint = *osFlag
+		case "snmp":
+			snmpOn = *snmpFlag
+		case "snmp-community":
+			snmpCreds = true
+		}
+	})
+	switch {
+	case !snmpOn:
+		opts.SNMP = nil
+	case opts.SNMP == nil || snmpCreds:
+		// las credenciales de los flags se suman a las del perfil
+		flagCreds := &scan.SNMPCredentials{V3: snmpV3}
+		for _, c := range strings.Split(*snmpComm, ",") {
+			if c = strings.TrimSpace(c); c != "" {
+				flagCreds.Communities = append(flagCreds.Communities, c)
+			}
+		}
+		opts.SNMP = opts.SNMP.Merge(flagCreds)
+	}
+	return opts
+}
+
+// showDiff modo -diff: cambios entre los dos últimos escaneos guardados de los
+// objetivos de la línea de comandos. Devuelve el código de salida.
+func showDiff(store *history.Store) int {
+	if store == nil {
+		fmt.Fprintln(os.Stderr, "-diff necesita el historial (-history)")
+		return 1
+	}
+	var excludes []string
+	if *excludeArg != "" {
+		excludes = append(excludes, *excludeArg)
+	}
+	runs, err := store.Last(history.RangeKey(flag.Args(), excludes), 2)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	if len(runs) < 2 {
+		fmt.Fprintf(os.Stderr, "hacen falta dos escaneos guardados de %q para comparar (hay %d)\n", strings.Join(flag.Args(), " "), len(runs))
+		return 1
+	}
+	changes := history.Diff(&runs[1], &runs[0])
+	if *jsonOut {
+		if changes == nil {
+			changes = []models.ChangeEvent{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(changes)
+		return 0
+	}
+	fmt.Printf("Cambios entre %s y %s: %d\n", runs[1].ID, runs[0].ID, len(changes))
+	for _, c := range changes {
+		fmt.Println(history.FormatChange(c))
+	}
+	return 0
 }
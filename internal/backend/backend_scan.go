@@ -4,13 +4,17 @@ import (
 	"bytes"
 	"encoding/json"
 	"escaner/internal/models"
-	scan "escaner/internal/utils"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"time"
 )
 
+// Log destino de los mensajes de envío; el CLI lo pasa a stderr cuando una
+// salida -o ocupa la salida estándar
+var Log io.Writer = os.Stdout
+
 func ifEmpty(s, fallback string) string {
 	if s == "" {
 		return fallback
@@ -22,16 +26,60 @@ func ifEmpty(s, fallback string) string {
 func SendToBackend(r models.Result, timeout time.Duration, backendURL string) error {
 	client := &http.Client{Timeout: timeout}
 
-	fmt.Println("Dispositivooooooooooooooo vivoooooooo detectadoooooooooooooooooooo:", scan.FormatResult(r))
-
-	dto := map[string]string{
+	dto := map[string]interface{}{
 		"ip":     r.IP,
 		"alive":  "sí",
 		"via":    r.Method,
 		"device": ifEmpty(r.DeviceType, "Unknown"),
 		"mac":    r.MAC,
+		"vendor": r.Vendor,
 		"name":   r.ReverseDNS,
 	}
+	if r.Hostname != "" {
+		dto["hostname"] = r.Hostname
+	}
+	if r.Model != "" {
+		dto["model"] = r.Model
+	}
+	if len(r.MDNSServices) > 0 {
+		dto["mdns_services"] = r.MDNSServices
+	}
+	if r.NetBIOSName != "" {
+		dto["netbios_name"] = r.NetBIOSName
+	}
+	if r.Workgroup != "" {
+		dto["workgroup"] = r.Workgroup
+	}
+	if r.SNMP != nil {
+		dto["snmp"] = r.SNMP
+	}
+	if r.UPnP != nil {
+		dto["upnp"] = r.UPnP
+	}
+	if len(r.TLS) > 0 {
+		dto["tls"] = r.TLS
+	}
+	if len(r.HTTP) > 0 {
+		dto["http"] = r.HTTP
+	}
+	if len(r.RTSP) > 0 {
+		dto["rtsp"] = r.RTSP
+	}
+	if len(r.SSH) > 0 {
+		dto["ssh"] = r.SSH
+	}
+	if r.OS != nil {
+		dto["os"] = r.OS
+	}
+	if len(r.OpenPorts) > 0 {
+		dto["open_ports"] = r.OpenPorts
+	}
+	if len(r.OpenUDPPorts) > 0 {
+		dto["open_udp_ports"] = r.OpenUDPPorts
+	}
+	if len(r.Services) > 0 {
+		dto["services"] = r.Services
+	}
 
 	body, err := json.Marshal(dto)
 	if err != nil {
@@ -55,18 +103,32 @@ func SendToBackend(r models.Result, timeout time.Duration, backendURL string) er
 		return fmt.Errorf("backend respondió error para %s: %d - %s", r.IP, resp.StatusCode, string(b))
 	}
 
-	fmt.Printf("Enviado (OK): %s\n", r.IP)
+	fmt.Fprintf(Log, "Enviado (OK): %s\n", r.IP)
 	return nil
 }
 
-func SendFinalMessage(timeout time.Duration, backendURL string, subred string) error {
+// SendFinalMessage avisa al backend que el escaneo de la subred terminó.
+// status es "ok" cuando el escaneo se completó, "cancelled" si fue abortado o
+// "error" si no pudo empezar (detail explica por qué).
+func SendFinalMessage(timeout time.Duration, backendURL string, subred string, status string, detail string) error {
 	client := &http.Client{Timeout: timeout}
 
+	message := "finalizado"
+	switch status {
+	case "cancelled":
+		message = "cancelado"
+	case "error":
+		message = "error"
+	}
+
 	finalDto := map[string]string{
-		"status":  "ok",
-		"message": "finalizado",
+		"status":  status,
+		"message": message,
 		"subred":  subred, // ✅ enviar la subred
 	}
+	if detail != "" {
+		finalDto["error"] = detail
+	}
 
 	body, err := json.Marshal(finalDto)
 	if err != nil {
@@ -89,6 +151,44 @@ func SendFinalMessage(timeout time.Duration, backendURL string, subred string) e
 		return fmt.Errorf("backend respondió con código: %d", resp.StatusCode)
 	}
 
-	fmt.Println("✅ Mensaje final enviado correctamente al backend")
+	fmt.Fprintln(Log, "✅ Mensaje final enviado correctamente al backend")
+	return nil
+}
+
+// SendChanges envía al backend los cambios de un escaneo respecto del anterior
+// del mismo rango (ver history.Diff)
+func SendChanges(run *models.ScanRun, previousID string, changes []models.ChangeEvent, timeout time.Duration, backendURL string) error {
+	client := &http.Client{Timeout: timeout}
+
+	dto := map[string]interface{}{
+		"agent":       run.Agent,
+		"range":       run.Range,
+		"scan_id":     run.ID,
+		"previous_id": previousID,
+		"finished":    run.Finished,
+		"changes":     changes,
+	}
+
+	body, err := json.Marshal(dto)
+	if err != nil {
+		return fmt.Errorf("error marshal cambios: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", backendURL, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("error creando request de cambios: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error enviando cambios: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("backend respondió error a los cambios: %d - %s", resp.StatusCode, string(b))
+	}
 	return nil
 }
@@ -0,0 +1,87 @@
+package export
+
+import (
+	"encoding/csv"
+	"escaner/internal/models"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvHeader columnas del CSV; las listas van separadas por ";"
+var csvHeader = []string{
+	"ip", "method", "port", "mac", "vendor", "name", "hostname", "netbios_name", "workgroup",
+	"device_type", "model", "os", "os_confidence", "rtt_ms", "ttl",
+	"open_ports", "open_udp_ports", "services",
+}
+
+// csvExporter una fila por equipo, escrita al terminar
+type csvExporter struct {
+	w io.Writer
+}
+
+func newCSV(w io.Writer) Exporter { return &csvExporter{w: w} }
+
+func (e *csvExporter) Result(models.Result) error { return nil }
+
+func (e *csvExporter) Finish(_ Meta, results []models.Result) error {
+	cw := csv.NewWriter(e.w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range results {
+		var osFamily, osConf string
+		if r.OS != nil {
+			osFamily = r.OS.Family
+			osConf = strconv.FormatFloat(r.OS.Confidence, 'f', 2, 64)
+		}
+		row := []string{
+			r.IP, r.Method, itoa(r.Port), r.MAC, r.Vendor, r.ReverseDNS, r.Hostname, r.NetBIOSName, r.Workgroup,
+			r.DeviceType, r.Model, osFamily, osConf, formatRTT(r.RTTMs), itoa(r.TTL),
+			joinInts(r.OpenPorts), joinInts(r.OpenUDPPorts), joinServices(r.Services),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// itoa vacío para 0
+func itoa(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func formatRTT(ms float64) string {
+	if ms == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(ms, 'f', 3, 64)
+}
+
+func joinInts(list []int) string {
+	parts := make([]string, len(list))
+	for i, n := range list {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ";")
+}
+
+// joinServices "22/tcp ssh OpenSSH 8.9p1;161/udp snmp"
+func joinServices(list []models.Service) string {
+	parts := make([]string, len(list))
+	for i, s := range list {
+		parts[i] = strings.TrimSpace(fmt.Sprintf("%d/%s %s", s.Port, s.Protocol, serviceLabel(s)))
+	}
+	return strings.Join(parts, ";")
+}
+
+// serviceLabel "ssh OpenSSH 8.9p1"
+func serviceLabel(s models.Service) string {
+	return strings.Join(strings.Fields(s.Name+" "+s.Product+" "+s.Version), " ")
+}
@@ -0,0 +1,175 @@
+// Package export escribe los resultados de un escaneo en distintos formatos
+// (csv, ndjson, html, nmap). Cada formato se registra con Register y se elige
+// con una especificación "formato:ruta".
+package export
+
+import (
+	"escaner/internal/models"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Meta datos del escaneo que acompañan a los resultados
+type Meta struct {
+	Args     []string // línea de comandos
+	Started  time.Time
+	Finished time.Time
+	Down     int // hosts que no respondieron
+}
+
+// Exporter formato de salida. Result se llama con cada equipo apenas termina de
+// escanearse (para formatos que se escriben en vivo) y Finish una sola vez con
+// todos los resultados ordenados. Ambos reciben solo los equipos vivos.
+type Exporter interface {
+	Result(r models.Result) error
+	Finish(meta Meta, results []models.Result) error
+}
+
+// Factory crea un exportador que escribe en w
+type Factory func(w io.Writer) Exporter
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register agrega (o reemplaza) un formato
+func Register(name string, f Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[strings.ToLower(name)] = f
+}
+
+// Formats nombres de los formatos registrados, ordenados
+func Formats() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func init() {
+	Register("csv", newCSV)
+	Register("ndjson", newNDJSON)
+	Register("html", newHTML)
+	Register("nmap", newNmapXML)
+	Register("xml", newNmapXML)
+}
+
+// output exportador junto con el archivo donde escribe
+type output struct {
+	spec string
+	exp  Exporter
+	file *os.File // nil para la salida estándar
+}
+
+// Set varias salidas de un mismo escaneo; seguro para usar desde varias goroutines
+type Set struct {
+	mu      sync.Mutex
+	outputs []*output
+}
+
+// parseSpec separa "formato:ruta" y busca el formato
+func parseSpec(spec string) (Factory, string, error) {
+	format, path, _ := strings.Cut(spec, ":")
+	registryMu.RLock()
+	factory, ok := registry[strings.ToLower(format)]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, "", fmt.Errorf("formato de salida desconocido %q (disponibles: %s)", format, strings.Join(Formats(), ", "))
+	}
+	return factory, path, nil
+}
+
+// Check valida la salida "formato:ruta" sin crear el archivo
+func Check(spec string) error {
+	_, _, err := parseSpec(spec)
+	return err
+}
+
+// ToStdout indica si la salida "formato:ruta" escribe en la salida estándar
+func ToStdout(spec string) bool {
+	_, path, _ := strings.Cut(spec, ":")
+	return path == "" || path == "-"
+}
+
+// Add abre la salida "formato:ruta" (ruta "-" o vacía = salida estándar)
+func (s *Set) Add(spec string) error {
+	factory, path, err := parseSpec(spec)
+	if err != nil {
+		return err
+	}
+	out := &output{spec: spec}
+	var w io.Writer = os.Stdout
+	if !ToStdout(spec) {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("error creando %s: %w", path, err)
+		}
+		out.file, w = f, f
+	}
+	out.exp = factory(w)
+	s.mu.Lock()
+	s.outputs = append(s.outputs, out)
+	s.mu.Unlock()
+	return nil
+}
+
+// Len cantidad de salidas
+func (s *Set) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.outputs)
+}
+
+// Result pasa un equipo a todas las salidas; devuelve el primer error
+func (s *Set) Result(r models.Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var first error
+	for _, o := range s.outputs {
+		if err := o.exp.Result(r); err != nil && first == nil {
+			first = fmt.Errorf("%s: %w", o.spec, err)
+		}
+	}
+	return first
+}
+
+// Close termina todas las salidas con los resultados finales y cierra los
+// archivos; devuelve el primer error. Los hosts muertos que vengan en results
+// no se exportan: se suman a meta.Down.
+func (s *Set) Close(meta Meta, results []models.Result) error {
+	var alive []models.Result
+	for _, r := range results {
+		if r.Alive {
+			alive = append(alive, r)
+		}
+	}
+	meta.Down += len(results) - len(alive)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var first error
+	for _, o := range s.outputs {
+		err := o.exp.Finish(meta, alive)
+		if o.file != nil {
+			if cerr := o.file.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil && first == nil {
+			first = fmt.Errorf("%s: %w", o.spec, err)
+		}
+	}
+	s.outputs = nil
+	return first
+}
@@ -0,0 +1,126 @@
+package export
+
+import (
+	"cmp"
+	"escaner/internal/models"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// htmlExporter informe autocontenido (sin recursos externos) agrupado por tipo de equipo
+type htmlExporter struct {
+	w io.Writer
+}
+
+func newHTML(w io.Writer) Exporter { return &htmlExporter{w: w} }
+
+func (e *htmlExporter) Result(models.Result) error { return nil }
+
+type htmlGroup struct {
+	Type    string
+	Results []models.Result
+}
+
+type htmlReport struct {
+	Meta    Meta
+	Args    string
+	Total   int
+	Elapsed time.Duration
+	Groups  []htmlGroup
+}
+
+func (e *htmlExporter) Finish(meta Meta, results []models.Result) error {
+	byType := map[string][]models.Result{}
+	for _, r := range results {
+		t := r.DeviceType
+		if t == "" {
+			t = "Unknown"
+		}
+		byType[t] = append(byType[t], r)
+	}
+	report := htmlReport{
+		Meta:    meta,
+		Args:    strings.Join(meta.Args, " "),
+		Total:   len(results),
+		Elapsed: meta.Finished.Sub(meta.Started).Round(time.Second),
+	}
+	for t, list := range byType {
+		report.Groups = append(report.Groups, htmlGroup{t, list})
+	}
+	// grupos más grandes primero; Unknown siempre al final
+	slices.SortFunc(report.Groups, func(a, b htmlGroup) int {
+		if (a.Type == "Unknown") != (b.Type == "Unknown") {
+			if a.Type == "Unknown" {
+				return 1
+			}
+			return -1
+		}
+		if c := cmp.Compare(len(b.Results), len(a.Results)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Type, b.Type)
+	})
+	return htmlTemplate.Execute(e.w, report)
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ports":    htmlPorts,
+	"services": htmlServices,
+	"os": func(g *models.OSGuess) string {
+		if g == nil {
+			return ""
+		}
+		return fmt.Sprintf("%s (%.0f%%)", g.Family, g.Confidence*100)
+	},
+	"date": func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+}).Parse(`<!DOCTYPE html>
+<html lang="es">
+<head>
+<meta charset="utf-8">
+<title>Escaneo de red {{date .Meta.Started}}</title>
+<style>
+body{font-family:system-ui,sans-serif;margin:2em;color:#222}
+h1{font-size:1.4em}
+h2{font-size:1.1em;margin-top:1.6em;border-bottom:2px solid #ccc}
+table{border-collapse:collapse;width:100%;font-size:.9em}
+th,td{border:1px solid #ddd;padding:.3em .5em;text-align:left;vertical-align:top}
+th{background:#f3f3f3}
+tr:nth-child(even) td{background:#fafafa}
+.meta{color:#666}
+nav a{margin-right:1em}
+</style>
+</head>
+<body>
+<h1>Escaneo de red</h1>
+<p class="meta">Inicio {{date .Meta.Started}} · duración {{.Elapsed}} · {{.Total}} equipos{{if .Args}} · <code>{{.Args}}</code>{{end}}</p>
+<nav>{{range .Groups}}<a href="#{{.Type}}">{{.Type}} ({{len .Results}})</a>{{end}}</nav>
+{{range .Groups}}
+<h2 id="{{.Type}}">{{.Type}} ({{len .Results}})</h2>
+<table>
+<tr><th>IP</th><th>MAC</th><th>Fabricante</th><th>Nombre</th><th>Modelo</th><th>Sistema</th><th>Puertos</th><th>Servicios</th></tr>
+{{range .Results}}<tr><td>{{.IP}}</td><td>{{.MAC}}</td><td>{{.Vendor}}</td><td>{{.ReverseDNS}}</td><td>{{.Model}}</td><td>{{os .OS}}</td><td>{{ports .}}</td><td>{{services .Services}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// htmlPorts "tcp 22, 80 · udp 161"
+func htmlPorts(r models.Result) string {
+	var parts []string
+	if len(r.OpenPorts) > 0 {
+		parts = append(parts, "tcp "+strings.ReplaceAll(joinInts(r.OpenPorts), ";", ", "))
+	}
+	if len(r.OpenUDPPorts) > 0 {
+		parts = append(parts, "udp "+strings.ReplaceAll(joinInts(r.OpenUDPPorts), ";", ", "))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func htmlServices(list []models.Service) string {
+	return strings.ReplaceAll(joinServices(list), ";", ", ")
+}
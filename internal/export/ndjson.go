@@ -0,0 +1,18 @@
+package export
+
+import (
+	"encoding/json"
+	"escaner/internal/models"
+	"io"
+)
+
+// ndjsonExporter un objeto JSON por línea, escrito apenas termina cada equipo
+type ndjsonExporter struct {
+	enc *json.Encoder
+}
+
+func newNDJSON(w io.Writer) Exporter { return &ndjsonExporter{enc: json.NewEncoder(w)} }
+
+func (e *ndjsonExporter) Result(r models.Result) error { return e.enc.Encode(r) }
+
+func (e *ndjsonExporter) Finish(Meta, []models.Result) error { return nil }
@@ -0,0 +1,238 @@
+package export
+
+import (
+	"cmp"
+	"encoding/xml"
+	"escaner/internal/models"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nmapExporter XML con el esquema de "nmap -oX" (nmaprun/host/ports/os), para
+// herramientas que ya lo importan. Sigue nmap.dtd: scanner tiene que ser "nmap",
+// así que el escáner se identifica en args.
+type nmapExporter struct {
+	w io.Writer
+}
+
+func newNmapXML(w io.Writer) Exporter { return &nmapExporter{w: w} }
+
+func (e *nmapExporter) Result(models.Result) error { return nil }
+
+type nmapRun struct {
+	XMLName          xml.Name   `xml:"nmaprun"`
+	Scanner          string     `xml:"scanner,attr"`
+	Args             string     `xml:"args,attr"`
+	Start            int64      `xml:"start,attr"`
+	StartStr         string     `xml:"startstr,attr"`
+	Version          string     `xml:"version,attr"`
+	XMLOutputVersion string     `xml:"xmloutputversion,attr"`
+	Verbose          nmapLevel  `xml:"verbose"`
+	Debugging        nmapLevel  `xml:"debugging"`
+	Hosts            []nmapHost `xml:"host"`
+	RunStats         struct {
+		Finished struct {
+			Time    int64  `xml:"time,attr"`
+			TimeStr string `xml:"timestr,attr"`
+			Elapsed string `xml:"elapsed,attr"`
+			Exit    string `xml:"exit,attr"`
+		} `xml:"finished"`
+		Hosts struct {
+			Up    int `xml:"up,attr"`
+			Down  int `xml:"down,attr"`
+			Total int `xml:"total,attr"`
+		} `xml:"hosts"`
+	} `xml:"runstats"`
+}
+
+type nmapLevel struct {
+	Level int `xml:"level,attr"`
+}
+
+type nmapHost struct {
+	Status struct {
+		State     string `xml:"state,attr"`
+		Reason    string `xml:"reason,attr"`
+		ReasonTTL int    `xml:"reason_ttl,attr"`
+	} `xml:"status"`
+	Addresses []nmapAddress `xml:"address"`
+	// nmap escribe <hostnames/> aunque no haya nombres
+	Hostnames struct {
+		List []nmapHostname `xml:"hostname"`
+	} `xml:"hostnames"`
+	Ports []nmapPort `xml:"ports>port"`
+	OS    *nmapOS    `xml:"os,omitempty"`
+	Times *nmapTimes `xml:"times,omitempty"`
+}
+
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+	Vendor   string `xml:"vendor,attr,omitempty"`
+}
+
+type nmapHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type nmapPort struct {
+	Protocol string `xml:"protocol,attr"`
+	PortID   int    `xml:"portid,attr"`
+	State    struct {
+		State     string `xml:"state,attr"`
+		Reason    string `xml:"reason,attr"`
+		ReasonTTL int    `xml:"reason_ttl,attr"`
+	} `xml:"state"`
+	Service *nmapService `xml:"service,omitempty"`
+}
+
+type nmapService struct {
+	Name       string `xml:"name,attr"`
+	Product    string `xml:"product,attr,omitempty"`
+	Version    string `xml:"version,attr,omitempty"`
+	ExtraInfo  string `xml:"extrainfo,attr,omitempty"`
+	DeviceType string `xml:"devicetype,attr,omitempty"`
+	Method     string `xml:"method,attr"`
+	Conf       int    `xml:"conf,attr"`
+}
+
+type nmapOS struct {
+	Match struct {
+		Name     string `xml:"name,attr"`
+		Accuracy int    `xml:"accuracy,attr"`
+		Line     int    `xml:"line,attr"` // línea de nmap-os-db; 0 = huella propia
+		Class    struct {
+			Vendor   string `xml:"vendor,attr"`
+			OSFamily string `xml:"osfamily,attr"`
+			Accuracy int    `xml:"accuracy,attr"`
+		} `xml:"osclass"`
+	} `xml:"osmatch"`
+}
+
+// fabricante del sistema de cada familia de la huella pasiva; las demás usan el
+// fabricante de la MAC
+var nmapOSVendors = map[string]string{
+	"Windows": "Microsoft",
+	"Linux":   "Linux",
+}
+
+type nmapTimes struct {
+	SRTT   int64 `xml:"srtt,attr"` // microsegundos
+	RTTVar int64 `xml:"rttvar,attr"`
+	To     int64 `xml:"to,attr"`
+}
+
+// razón de "host up" al estilo nmap según cómo se detectó
+var nmapReasons = map[string]string{
+	"icmp": "echo-reply",
+	"tcp":  "syn-ack",
+	"udp":  "udp-response",
+	"mdns": "mdns-response",
+	"ssdp": "ssdp-response",
+}
+
+func (e *nmapExporter) Finish(meta Meta, results []models.Result) error {
+	run := nmapRun{
+		Scanner:          "nmap",
+		Args:             strings.Join(append([]string{"escaner"}, meta.Args...), " "),
+		Start:            meta.Started.Unix(),
+		StartStr:         meta.Started.Format(time.ANSIC),
+		Version:          "1.0",
+		XMLOutputVersion: "1.05",
+	}
+	for _, r := range results {
+		run.Hosts = append(run.Hosts, nmapHostFrom(r))
+	}
+	run.RunStats.Finished.Time = meta.Finished.Unix()
+	run.RunStats.Finished.TimeStr = meta.Finished.Format(time.ANSIC)
+	run.RunStats.Finished.Elapsed = strconv.FormatFloat(meta.Finished.Sub(meta.Started).Seconds(), 'f', 2, 64)
+	run.RunStats.Finished.Exit = "success"
+	run.RunStats.Hosts.Up = len(results)
+	run.RunStats.Hosts.Down = meta.Down
+	run.RunStats.Hosts.Total = len(results) + meta.Down
+
+	if _, err := io.WriteString(e.w, xml.Header+"<!DOCTYPE nmaprun>\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(e.w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(run); err != nil {
+		return err
+	}
+	_, err := io.WriteString(e.w, "\n")
+	return err
+}
+
+func nmapHostFrom(r models.Result) nmapHost {
+	var h nmapHost
+	h.Status.State = "up"
+	h.Status.Reason = nmapReasons[r.Method]
+	h.Status.ReasonTTL = r.TTL
+
+	addrType := "ipv4"
+	if a, err := netip.ParseAddr(r.IP); err == nil && a.Unmap().Is6() {
+		addrType = "ipv6"
+	}
+	h.Addresses = append(h.Addresses, nmapAddress{Addr: r.IP, AddrType: addrType})
+	if r.MAC != "" {
+		h.Addresses = append(h.Addresses, nmapAddress{Addr: strings.ToUpper(r.MAC), AddrType: "mac", Vendor: r.Vendor})
+	}
+	if r.ReverseDNS != "" {
+		h.Hostnames.List = append(h.Hostnames.List, nmapHostname{Name: r.ReverseDNS, Type: "PTR"})
+	}
+
+	// sin enumeración completa, al menos el puerto que respondió
+	tcp, udp := r.OpenPorts, r.OpenUDPPorts
+	if tcp == nil && r.Method == "tcp" && r.Port > 0 {
+		tcp = []int{r.Port}
+	}
+	if udp == nil && r.Method == "udp" && r.Port > 0 {
+		udp = []int{r.Port}
+	}
+	for _, p := range tcp {
+		h.Ports = append(h.Ports, nmapPortFrom("tcp", p, "syn-ack", r.Services))
+	}
+	for _, p := range udp {
+		h.Ports = append(h.Ports, nmapPortFrom("udp", p, "udp-response", r.Services))
+	}
+
+	if r.OS != nil {
+		acc := int(r.OS.Confidence * 100)
+		h.OS = &nmapOS{}
+		h.OS.Match.Name = r.OS.Family
+		h.OS.Match.Accuracy = acc
+		h.OS.Match.Class.Vendor = cmp.Or(nmapOSVendors[r.OS.Family], r.Vendor, "unknown")
+		h.OS.Match.Class.OSFamily = r.OS.Family
+		h.OS.Match.Class.Accuracy = acc
+	}
+	if r.RTTMs > 0 {
+		us := int64(r.RTTMs * 1000)
+		h.Times = &nmapTimes{SRTT: us, RTTVar: us / 2, To: us * 3}
+	}
+	return h
+}
+
+func nmapPortFrom(proto string, port int, reason string, services []models.Service) nmapPort {
+	p := nmapPort{Protocol: proto, PortID: port}
+	p.State.State = "open"
+	p.State.Reason = reason
+	for _, s := range services {
+		if s.Port == port && s.Protocol == proto {
+			p.Service = &nmapService{
+				Name:       s.Name,
+				Product:    s.Product,
+				Version:    s.Version,
+				ExtraInfo:  s.Info,
+				DeviceType: s.DeviceType,
+				Method:     "probed",
+				Conf:       10,
+			}
+			break
+		}
+	}
+	return p
+}
@@ -0,0 +1,132 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"escaner/internal/models"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// nmapTestResults un host completo (MAC, PTR, puertos, servicios, sistema, RTT)
+// y uno mínimo sin nombres ni sistema
+func nmapTestResults() []models.Result {
+	return []models.Result{
+		{
+			IP: "192.168.1.10", Alive: true, Method: "icmp", TTL: 64, RTTMs: 1.25,
+			MAC: "aa:bb:cc:dd:ee:ff", Vendor: "Raspberry Pi Trading Ltd", ReverseDNS: "pi.lan",
+			OpenPorts: []int{22, 80}, OpenUDPPorts: []int{161},
+			Services: []models.Service{
+				{Port: 22, Protocol: "tcp", Name: "ssh", Product: "OpenSSH", Version: "8.9p1"},
+				{Port: 161, Protocol: "udp", Name: "snmp"},
+			},
+			OS: &models.OSGuess{Family: "Linux", Confidence: 0.7},
+		},
+		{IP: "192.168.1.20", Alive: true, Method: "tcp", Port: 443},
+	}
+}
+
+func writeNmapXML(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	started := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	meta := Meta{Args: []string{"-o", "nmap:out.xml", "192.168.1.0/24"}, Started: started, Finished: started.Add(42 * time.Second), Down: 252}
+	if err := newNmapXML(&buf).Finish(meta, nmapTestResults()); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestNmapXMLStructure lee la salida con la forma de nmap -oX que esperan los
+// importadores (elementos y atributos obligatorios de nmap.dtd)
+func TestNmapXMLStructure(t *testing.T) {
+	var run struct {
+		Scanner string `xml:"scanner,attr"`
+		Verbose *struct {
+			Level string `xml:"level,attr"`
+		} `xml:"verbose"`
+		Debugging *struct {
+			Level string `xml:"level,attr"`
+		} `xml:"debugging"`
+		Hosts []struct {
+			Hostnames *struct {
+				List []struct {
+					Name string `xml:"name,attr"`
+					Type string `xml:"type,attr"`
+				} `xml:"hostname"`
+			} `xml:"hostnames"`
+			Ports []struct {
+				State struct {
+					ReasonTTL *string `xml:"reason_ttl,attr"`
+				} `xml:"state"`
+			} `xml:"ports>port"`
+			OSMatch []struct {
+				Line  *string `xml:"line,attr"`
+				Class []struct {
+					Vendor   string `xml:"vendor,attr"`
+					OSFamily string `xml:"osfamily,attr"`
+				} `xml:"osclass"`
+			} `xml:"os>osmatch"`
+		} `xml:"host"`
+		Stats struct {
+			Up    int `xml:"up,attr"`
+			Down  int `xml:"down,attr"`
+			Total int `xml:"total,attr"`
+		} `xml:"runstats>hosts"`
+	}
+	if err := xml.Unmarshal(writeNmapXML(t), &run); err != nil {
+		t.Fatal(err)
+	}
+
+	if run.Scanner != "nmap" {
+		t.Errorf("scanner = %q, quiero nmap", run.Scanner)
+	}
+	if run.Verbose == nil || run.Debugging == nil {
+		t.Fatal("faltan <verbose> o <debugging>")
+	}
+	if len(run.Hosts) != 2 {
+		t.Fatalf("%d hosts, quiero 2", len(run.Hosts))
+	}
+	for i, h := range run.Hosts {
+		if h.Hostnames == nil {
+			t.Errorf("host %d sin <hostnames>", i)
+		}
+		for _, p := range h.Ports {
+			if p.State.ReasonTTL == nil {
+				t.Errorf("host %d: <state> sin reason_ttl", i)
+			}
+		}
+	}
+	if n := len(run.Hosts[0].Hostnames.List); n != 1 || run.Hosts[0].Hostnames.List[0].Type != "PTR" {
+		t.Errorf("hostnames del host 0 = %+v", run.Hosts[0].Hostnames.List)
+	}
+	if len(run.Hosts[0].Ports) != 3 {
+		t.Errorf("%d puertos en el host 0, quiero 3", len(run.Hosts[0].Ports))
+	}
+	m := run.Hosts[0].OSMatch
+	if len(m) != 1 || m[0].Line == nil || len(m[0].Class) != 1 || m[0].Class[0].Vendor != "Linux" || m[0].Class[0].OSFamily != "Linux" {
+		t.Errorf("osmatch = %+v", m)
+	}
+	if run.Stats.Up != 2 || run.Stats.Down != 252 || run.Stats.Total != 254 {
+		t.Errorf("runstats = %+v", run.Stats)
+	}
+}
+
+// TestNmapXMLValidDTD valida la salida contra nmap.dtd con xmllint (si está instalado)
+func TestNmapXMLValidDTD(t *testing.T) {
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Skip("xmllint no disponible")
+	}
+	path := filepath.Join(t.TempDir(), "scan.xml")
+	if err := os.WriteFile(path, writeNmapXML(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(xmllint, "--noout", "--dtdvalid", filepath.Join("testdata", "nmap.dtd"), path).CombinedOutput()
+	if err != nil {
+		t.Fatalf("xmllint: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,168 @@
+package history
+
+import (
+	"escaner/internal/models"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// tipos de ChangeEvent
+const (
+	Appeared     = "appeared"
+	Disappeared  = "disappeared"
+	IPChanged    = "ip_changed"
+	MACChanged   = "mac_changed"
+	PortsChanged = "ports_changed"
+	TypeChanged  = "type_changed"
+)
+
+// Diff compara dos escaneos del mismo rango. Un equipo es el mismo si conserva
+// la MAC (aunque cambie de IP) o, sin MAC que coincida, si conserva la IP.
+// Los puertos solo se comparan si ambos escaneos los enumeraron completos.
+func Diff(prev, cur *models.ScanRun) []models.ChangeEvent {
+	if prev == nil || cur == nil {
+		return nil
+	}
+	before := alive(prev.Results)
+	after := alive(cur.Results)
+	comparePorts := prev.PortsEnumerated && cur.PortsEnumerated
+
+	// emparejar primero por MAC y después por IP, para que un equipo que se mudó
+	// no quede emparejado con el que ocupó su IP anterior. Con MACs repetidas
+	// (router con varias IPs, proxy ARP) se prefiere la que además conserva la IP.
+	match := make([]int, len(after))
+	used := make([]bool, len(before))
+	for i := range match {
+		match[i] = -1
+	}
+	pair := func(same func(p, r models.Result) bool) {
+		for i, r := range after {
+			if match[i] >= 0 {
+				continue
+			}
+			for j, p := range before {
+				if !used[j] && same(p, r) {
+					match[i], used[j] = j, true
+					break
+				}
+			}
+		}
+	}
+	sameMAC := func(p, r models.Result) bool { return r.MAC != "" && strings.EqualFold(p.MAC, r.MAC) }
+	pair(func(p, r models.Result) bool { return sameMAC(p, r) && p.IP == r.IP })
+	pair(sameMAC)
+	pair(func(p, r models.Result) bool { return p.IP == r.IP })
+
+	var events []models.ChangeEvent
+	for i, r := range after {
+		if match[i] < 0 {
+			events = append(events, event(Appeared, r))
+			continue
+		}
+		p := before[match[i]]
+		if p.IP != r.IP {
+			e := event(IPChanged, r)
+			e.Before, e.After = p.IP, r.IP
+			events = append(events, e)
+		}
+		if p.MAC != "" && r.MAC != "" && !strings.EqualFold(p.MAC, r.MAC) {
+			e := event(MACChanged, r)
+			e.Before, e.After = p.MAC, r.MAC
+			events = append(events, e)
+		}
+		if p.DeviceType != "" && r.DeviceType != "" && p.DeviceType != r.DeviceType {
+			e := event(TypeChanged, r)
+			e.Before, e.After = p.DeviceType, r.DeviceType
+			events = append(events, e)
+		}
+		if comparePorts {
+			old, now := portSet(p), portSet(r)
+			opened := without(now, old)
+			closed := without(old, now)
+			if len(opened) > 0 || len(closed) > 0 {
+				e := event(PortsChanged, r)
+				e.Opened, e.Closed = opened, closed
+				events = append(events, e)
+			}
+		}
+	}
+	for j, p := range before {
+		if !used[j] {
+			events = append(events, event(Disappeared, p))
+		}
+	}
+	return events
+}
+
+func alive(results []models.Result) []models.Result {
+	var out []models.Result
+	for _, r := range results {
+		if r.Alive {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func event(kind string, r models.Result) models.ChangeEvent {
+	return models.ChangeEvent{
+		Type:       kind,
+		IP:         r.IP,
+		MAC:        r.MAC,
+		Name:       r.ReverseDNS,
+		DeviceType: r.DeviceType,
+	}
+}
+
+// portSet puertos abiertos como "tcp/22" y "udp/161", ordenados
+func portSet(r models.Result) []string {
+	var out []string
+	for _, p := range r.OpenPorts {
+		out = append(out, "tcp/"+strconv.Itoa(p))
+	}
+	for _, p := range r.OpenUDPPorts {
+		out = append(out, "udp/"+strconv.Itoa(p))
+	}
+	slices.Sort(out)
+	return out
+}
+
+// without elementos de a que no están en b
+func without(a, b []string) []string {
+	var out []string
+	for _, s := range a {
+		if !slices.Contains(b, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// FormatChange línea legible de un cambio para la salida de texto
+func FormatChange(e models.ChangeEvent) string {
+	var detail string
+	switch e.Type {
+	case IPChanged, MACChanged, TypeChanged:
+		detail = e.Before + " -> " + e.After
+	case PortsChanged:
+		var parts []string
+		for _, p := range e.Opened {
+			parts = append(parts, "+"+p)
+		}
+		for _, p := range e.Closed {
+			parts = append(parts, "-"+p)
+		}
+		detail = strings.Join(parts, " ")
+	}
+	return strings.TrimRight(fmt.Sprintf("%-14s %-16s mac:%-18s %-12s %-24s %s",
+		e.Type, e.IP, ifEmpty(e.MAC, "-"), ifEmpty(e.DeviceType, "-"), ifEmpty(e.Name, "-"), detail), " ")
+}
+
+func ifEmpty(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
@@ -0,0 +1,118 @@
+package history
+
+import (
+	"escaner/internal/models"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func host(ip, mac string, ports ...int) models.Result {
+	return models.Result{IP: ip, MAC: mac, Alive: true, OpenPorts: ports}
+}
+
+// resumen "tipo ip antes>después +abiertos -cerrados" para comparar eventos
+func summary(events []models.ChangeEvent) []string {
+	var out []string
+	for _, e := range events {
+		s := e.Type + " " + e.IP
+		if e.Before != "" || e.After != "" {
+			s += " " + e.Before + ">" + e.After
+		}
+		if len(e.Opened) > 0 || len(e.Closed) > 0 {
+			s += fmt.Sprintf(" +%v -%v", e.Opened, e.Closed)
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestDiff(t *testing.T) {
+	cases := []struct {
+		name       string
+		before     []models.Result
+		after      []models.Result
+		enumerated [2]bool // PortsEnumerated de prev y cur
+		want       []string
+	}{
+		{
+			name:   "sin cambios",
+			before: []models.Result{host("10.0.0.1", "aa:aa:aa:aa:aa:01"), host("10.0.0.2", "")},
+			after:  []models.Result{host("10.0.0.1", "AA:AA:AA:AA:AA:01"), host("10.0.0.2", "")},
+		},
+		{
+			name:   "equipo que cambió de IP",
+			before: []models.Result{host("10.0.0.5", "aa:aa:aa:aa:aa:01")},
+			after:  []models.Result{host("10.0.0.9", "aa:aa:aa:aa:aa:01")},
+			want:   []string{"ip_changed 10.0.0.9 10.0.0.5>10.0.0.9"},
+		},
+		{
+			name:   "IPs intercambiadas",
+			before: []models.Result{host("10.0.0.5", "aa:aa:aa:aa:aa:01"), host("10.0.0.9", "aa:aa:aa:aa:aa:02")},
+			after:  []models.Result{host("10.0.0.5", "aa:aa:aa:aa:aa:02"), host("10.0.0.9", "aa:aa:aa:aa:aa:01")},
+			want:   []string{"ip_changed 10.0.0.5 10.0.0.9>10.0.0.5", "ip_changed 10.0.0.9 10.0.0.5>10.0.0.9"},
+		},
+		{
+			name:   "MAC repetida, una IP se movió",
+			before: []models.Result{host("10.0.0.1", "cc:cc:cc:cc:cc:01"), host("10.0.0.2", "cc:cc:cc:cc:cc:01")},
+			after:  []models.Result{host("10.0.0.1", "cc:cc:cc:cc:cc:01"), host("10.0.0.3", "cc:cc:cc:cc:cc:01")},
+			want:   []string{"ip_changed 10.0.0.3 10.0.0.2>10.0.0.3"},
+		},
+		{
+			name:   "MAC repetida, queda solo la segunda IP",
+			before: []models.Result{host("10.0.0.1", "cc:cc:cc:cc:cc:01"), host("10.0.0.2", "cc:cc:cc:cc:cc:01")},
+			after:  []models.Result{host("10.0.0.2", "cc:cc:cc:cc:cc:01")},
+			want:   []string{"disappeared 10.0.0.1"},
+		},
+		{
+			name:   "sin MAC se empareja por IP",
+			before: []models.Result{host("10.0.0.1", ""), host("10.0.0.2", "")},
+			after:  []models.Result{host("10.0.0.2", ""), host("10.0.0.3", "")},
+			want:   []string{"appeared 10.0.0.3", "disappeared 10.0.0.1"},
+		},
+		{
+			name:   "otro equipo ocupó la IP",
+			before: []models.Result{host("10.0.0.1", "aa:aa:aa:aa:aa:01")},
+			after:  []models.Result{host("10.0.0.1", "aa:aa:aa:aa:aa:02")},
+			want:   []string{"mac_changed 10.0.0.1 aa:aa:aa:aa:aa:01>aa:aa:aa:aa:aa:02"},
+		},
+		{
+			name:   "los hosts muertos no cuentan",
+			before: []models.Result{host("10.0.0.1", ""), {IP: "10.0.0.2"}},
+			after:  []models.Result{{IP: "10.0.0.1"}, host("10.0.0.2", "")},
+			want:   []string{"appeared 10.0.0.2", "disappeared 10.0.0.1"},
+		},
+		{
+			name:       "puertos con ambos escaneos enumerados",
+			before:     []models.Result{host("10.0.0.1", "", 22, 80)},
+			after:      []models.Result{host("10.0.0.1", "", 22, 443)},
+			enumerated: [2]bool{true, true},
+			want:       []string{"ports_changed 10.0.0.1 +[tcp/443] -[tcp/80]"},
+		},
+		{
+			name:       "puertos sin enumeración completa no se comparan",
+			before:     []models.Result{host("10.0.0.1", "", 22, 80)},
+			after:      []models.Result{host("10.0.0.1", "", 22)},
+			enumerated: [2]bool{true, false},
+		},
+	}
+	for _, c := range cases {
+		prev := &models.ScanRun{Results: c.before, PortsEnumerated: c.enumerated[0]}
+		cur := &models.ScanRun{Results: c.after, PortsEnumerated: c.enumerated[1]}
+		if got := summary(Diff(prev, cur)); !slices.Equal(got, c.want) {
+			t.Errorf("%s: Diff = %q, quiero %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestDiffTypeChanged(t *testing.T) {
+	a, b := host("10.0.0.1", ""), host("10.0.0.1", "")
+	a.DeviceType, b.DeviceType = "PC", "Printer"
+	got := Diff(&models.ScanRun{Results: []models.Result{a}}, &models.ScanRun{Results: []models.Result{b}})
+	if len(got) != 1 || got[0].Type != TypeChanged || got[0].Before != "PC" || got[0].After != "Printer" {
+		t.Errorf("Diff = %+v", got)
+	}
+	if Diff(nil, &models.ScanRun{}) != nil {
+		t.Error("quiero nil sin escaneo anterior")
+	}
+}
@@ -0,0 +1,203 @@
+// Package history guarda localmente cada escaneo y calcula qué cambió respecto
+// del escaneo anterior del mismo rango.
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"escaner/internal/models"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultKeep escaneos que se conservan por rango
+const DefaultKeep = 50
+
+// idLayout formato de ScanRun.ID: UTC y ordenable como texto
+const idLayout = "20060102T150405.000000000Z"
+
+// Store historial en disco: un directorio por rango y un JSON por escaneo
+type Store struct {
+	dir  string
+	Keep int // escaneos por rango que se conservan (<= 0 = todos)
+}
+
+// DefaultDir directorio del historial dentro de la configuración del usuario
+func DefaultDir() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "escaner-history"
+	}
+	return filepath.Join(base, "escaner", "history")
+}
+
+// Open abre (y crea si hace falta) el historial en dir
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creando historial: %w", err)
+	}
+	return &Store{dir: dir, Keep: DefaultKeep}, nil
+}
+
+// RangeKey normaliza objetivos y exclusiones: el mismo conjunto en otro orden
+// es el mismo rango
+func RangeKey(targets, exclude []string) string {
+	key := strings.Join(sortedFields(targets), " ")
+	if ex := sortedFields(exclude); len(ex) > 0 {
+		key += " !" + strings.Join(ex, ",")
+	}
+	return key
+}
+
+func sortedFields(list []string) []string {
+	var out []string
+	for _, s := range list {
+		for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+			out = append(out, f)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+func (s *Store) rangeDir(rangeKey string) string {
+	sum := sha256.Sum256([]byte(rangeKey))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:8]))
+}
+
+// Save guarda el escaneo (le asigna ID según Started si no tiene) y descarta
+// los más viejos del rango por encima de Keep
+func (s *Store) Save(run *models.ScanRun) error {
+	if run.ID == "" {
+		run.ID = run.Started.UTC().Format(idLayout)
+	}
+	dir := s.rangeDir(run.Range)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("error creando historial: %w", err)
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("error serializando escaneo: %w", err)
+	}
+	// escribir aparte y renombrar: un corte a mitad no deja un JSON roto
+	tmp, err := os.CreateTemp(dir, "run-*.tmp")
+	if err != nil {
+		return fmt.Errorf("error guardando escaneo: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error guardando escaneo: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error guardando escaneo: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, run.ID+".json")); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error guardando escaneo: %w", err)
+	}
+	return s.prune(dir)
+}
+
+// ids escaneos guardados del directorio, del más viejo al más nuevo
+func ids(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo historial: %w", err)
+	}
+	var out []string
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() {
+			out = append(out, id)
+		}
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+func (s *Store) prune(dir string) error {
+	if s.Keep <= 0 {
+		return nil
+	}
+	list, err := ids(dir)
+	if err != nil {
+		return err
+	}
+	for len(list) > s.Keep {
+		if err := os.Remove(filepath.Join(dir, list[0]+".json")); err != nil {
+			return fmt.Errorf("error depurando historial: %w", err)
+		}
+		list = list[1:]
+	}
+	return nil
+}
+
+// Last hasta n escaneos del rango, del más nuevo al más viejo
+func (s *Store) Last(rangeKey string, n int) ([]models.ScanRun, error) {
+	dir := s.rangeDir(rangeKey)
+	list, err := ids(dir)
+	if err != nil {
+		return nil, err
+	}
+	var runs []models.ScanRun
+	for i := len(list) - 1; i >= 0 && len(runs) < n; i-- {
+		data, err := os.ReadFile(filepath.Join(dir, list[i]+".json"))
+		if err != nil {
+			return nil, fmt.Errorf("error leyendo historial: %w", err)
+		}
+		var run models.ScanRun
+		if err := json.Unmarshal(data, &run); err != nil {
+			return nil, fmt.Errorf("escaneo %s dañado: %w", list[i], err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// Latest último escaneo guardado del rango; nil si no hay ninguno
+func (s *Store) Latest(rangeKey string) (*models.ScanRun, error) {
+	runs, err := s.Last(rangeKey, 1)
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return &runs[0], nil
+}
+
+// NewRun arma el registro de un escaneo terminado
+func NewRun(agent string, targets, exclude []string, started time.Time, results []models.Result) *models.ScanRun {
+	// los hosts muertos no aportan nada al historial y lo harían crecer con el rango
+	results = alive(results)
+	return &models.ScanRun{
+		Agent:    agent,
+		Range:    RangeKey(targets, exclude),
+		Targets:  targets,
+		Exclude:  exclude,
+		Started:  started,
+		Finished: time.Now(),
+		Results:  results,
+	}
+}
+
+// Record guarda run y devuelve sus cambios respecto del escaneo anterior del
+// mismo rango junto con el ID de ese escaneo (sin cambios si es el primero).
+// Si el anterior no se puede leer, run se guarda igual y se devuelve el error.
+func (s *Store) Record(run *models.ScanRun) ([]models.ChangeEvent, string, error) {
+	prev, prevErr := s.Latest(run.Range)
+	if err := s.Save(run); err != nil {
+		return nil, "", err
+	}
+	if prev == nil {
+		return nil, "", prevErr
+	}
+	return Diff(prev, run), prev.ID, nil
+}
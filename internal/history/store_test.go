@@ -0,0 +1,23 @@
+package history
+
+import "testing"
+
+func TestRangeKey(t *testing.T) {
+	cases := []struct {
+		targets, exclude []string
+		want             string
+	}{
+		{[]string{"192.168.1.0/24"}, nil, "192.168.1.0/24"},
+		{[]string{"10.0.0.2", "10.0.0.1"}, nil, "10.0.0.1 10.0.0.2"},
+		{[]string{"10.0.0.1,10.0.0.2"}, nil, "10.0.0.1 10.0.0.2"},
+		{[]string{"10.0.0.2 10.0.0.1", "10.0.0.1"}, nil, "10.0.0.1 10.0.0.2"},
+		{[]string{"10.0.0.0/24"}, []string{"10.0.0.9,10.0.0.1"}, "10.0.0.0/24 !10.0.0.1,10.0.0.9"},
+		{[]string{"10.0.0.0/24"}, []string{"10.0.0.1", " "}, "10.0.0.0/24 !10.0.0.1"},
+		{[]string{"10.0.0.0/24"}, []string{""}, "10.0.0.0/24"},
+	}
+	for _, c := range cases {
+		if got := RangeKey(c.targets, c.exclude); got != c.want {
+			t.Errorf("RangeKey(%q, %q) = %q, quiero %q", c.targets, c.exclude, got, c.want)
+		}
+	}
+}
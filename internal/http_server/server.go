@@ -27,7 +27,10 @@ func RunHTTPServer(
 		}
 
 		type ScanRequest struct {
-			Subred string `json:"subred"`
+			Subred   string `json:"subred"`
+			AllPorts bool   `json:"all_ports"`
+			// perfil de escaneo; sin perfil se usan los puertos y tiempos del agente
+			Profile string `json:"profile"`
 		}
 
 		var req ScanRequest
@@ -47,14 +50,20 @@ func RunHTTPServer(
 		ipRange := fmt.Sprintf("192.168.%d.1-255", subredInt)
 		fmt.Printf("Escaneando subred: %s\n", ipRange)
 
-		ips, err := scan.ExpandArgToIPs(ipRange)
+		targets, err := scan.ParseTargets([]string{ipRange}, nil)
 		if err != nil {
 			http.Error(w, "Error generando IPs: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		ports := scan.ParsePorts(portsArg)
-		timeout := time.Duration(timeoutMs) * time.Millisecond
+		profile, err := scan.LookupProfile(scan.DefaultProfile)
+		if req.Profile != "" {
+			profile, err = scan.LookupProfile(req.Profile)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		var aliveCount int64 = 0
 
@@ -69,7 +78,15 @@ func RunHTTPServer(
 			}
 		}
 
-		results := scan.ScanIPs(ips, ports, timeout, concurrency, onAlive)
+		// si el cliente HTTP corta la conexión se cancela el escaneo
+		opts := profile.Options()
+		if req.Profile == "" {
+			opts.Ports, opts.UDPPorts = scan.ParsePortSpec(portsArg)
+			opts.Timeout = time.Duration(timeoutMs) * time.Millisecond
+			opts.Concurrency = concurrency
+		}
+		opts.AllPorts = opts.AllPorts || req.AllPorts
+		results, down := scan.ScanIPs(r.Context(), targets.All(), opts, onAlive)
 
 		// Opcional: imprimir todos los resultados al final
 		for _, res := range results {
@@ -84,6 +101,7 @@ func RunHTTPServer(
 			"status":  "ok",
 			"message": "Escaneo completado",
 			"alive":   aliveCount,
+			"down":    down,
 		})
 
 	})
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// ScanRun un escaneo completo guardado en el historial local
+type ScanRun struct {
+	ID       string    `json:"id"`    // marca de tiempo UTC, ordenable
+	Agent    string    `json:"agent"` // agente que escaneó (MAC con la que se registra)
+	Range    string    `json:"range"` // objetivos normalizados: identifica "el mismo rango"
+	Targets  []string  `json:"targets"`
+	Exclude  []string  `json:"exclude,omitempty"`
+	Profile  string    `json:"profile,omitempty"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	// PortsEnumerated todos los puertos de cada host fueron sondeados (AllPorts o
+	// servicios); sin esto la lista de puertos no se compara entre escaneos
+	PortsEnumerated bool     `json:"ports_enumerated,omitempty"`
+	Results         []Result `json:"results"`
+}
+
+// ChangeEvent diferencia de un equipo entre dos escaneos del mismo rango
+type ChangeEvent struct {
+	Type       string   `json:"type"` // appeared, disappeared, ip_changed, mac_changed, ports_changed o type_changed
+	IP         string   `json:"ip"`   // IP actual (la última conocida si desapareció)
+	MAC        string   `json:"mac,omitempty"`
+	Name       string   `json:"name,omitempty"`
+	DeviceType string   `json:"device_type,omitempty"`
+	Before     string   `json:"before,omitempty"` // valor anterior de IP, MAC o tipo
+	After      string   `json:"after,omitempty"`
+	Opened     []string `json:"opened,omitempty"` // puertos nuevos: "tcp/22", "udp/161"
+	Closed     []string `json:"closed,omitempty"`
+}
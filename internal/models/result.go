@@ -1,11 +1,128 @@
 package models
 
+import "time"
+
 type Result struct {
-	IP         string `json:"ip"`
-	Alive      bool   `json:"alive"`
-	Method     string `json:"method,omitempty"`
-	Port       int    `json:"port,omitempty"`
-	MAC        string `json:"mac,omitempty"`
-	ReverseDNS string `json:"reverse_dns,omitempty"`
+	IP           string     `json:"ip"`
+	Alive        bool       `json:"alive"`
+	Method       string     `json:"method,omitempty"`         // icmp, tcp, udp, mdns o ssdp
+	Port         int        `json:"port,omitempty"`           // puerto que respondió (protocolo según Method)
+	OpenPorts    []int      `json:"open_ports,omitempty"`     // todos los puertos TCP abiertos (modo AllPorts)
+	OpenUDPPorts []int      `json:"open_udp_ports,omitempty"` // puertos UDP con respuesta válida
+	MAC          string     `json:"mac,omitempty"`
+	Vendor       string     `json:"vendor,omitempty"` // fabricante según el registro OUI del IEEE
+	ReverseDNS   string     `json:"reverse_dns,omitempty"`
+	DeviceType   string     `json:"device_type,omitempty"`
+	Hostname     string     `json:"hostname,omitempty"`      // nombre .local anunciado por mDNS
+	Model        string     `json:"model,omitempty"`         // modelo anunciado por mDNS (TXT model/md/ty) o UPnP
+	MDNSServices []string   `json:"mdns_services,omitempty"` // tipos DNS-SD anunciados (_ipp._tcp, _airplay._tcp...)
+	NetBIOSName  string     `json:"netbios_name,omitempty"`  // nombre del equipo según NBSTAT (Windows/Samba)
+	Workgroup    string     `json:"workgroup,omitempty"`     // grupo de trabajo o dominio NetBIOS
+	RTTMs        float64    `json:"rtt_ms,omitempty"`        // tiempo de ida y vuelta del ICMP echo
+	TTL          int        `json:"ttl,omitempty"`           // TTL de la respuesta ICMP
+	OS           *OSGuess   `json:"os,omitempty"`            // familia de sistema según TTL y SYN-ACK
+	SNMP         *SNMPInfo  `json:"snmp,omitempty"`          // grupo system leído por SNMP
+	UPnP         *UPnPInfo  `json:"upnp,omitempty"`          // descripción UPnP del equipo (SSDP)
+	TLS          []TLSCert  `json:"tls,omitempty"`           // certificados de los puertos TLS abiertos
+	HTTP         []HTTPInfo `json:"http,omitempty"`          // huella de cada puerto web abierto
+	RTSP         []RTSPInfo `json:"rtsp,omitempty"`          // respuesta de cada puerto RTSP abierto
+	SSH          []SSHInfo  `json:"ssh,omitempty"`           // identificación de cada puerto SSH abierto
+	Services     []Service  `json:"services,omitempty"`      // servicio/versión por puerto abierto
+}
+
+// OSGuess familia de sistema deducida pasivamente del TTL y del SYN-ACK de un
+// puerto abierto, con las señales que se usaron
+type OSGuess struct {
+	Family     string  `json:"family"`                // Windows, Linux, BSD/macOS, Embedded RTOS o Network OS
+	Confidence float64 `json:"confidence"`            // 0 a 1
+	Source     string  `json:"source"`                // ttl, syn-ack o tcp_info
+	TTL        int     `json:"ttl,omitempty"`         // observado
+	InitialTTL int     `json:"initial_ttl,omitempty"` // 32, 64, 128 o 255
+	Window     int     `json:"window,omitempty"`      // ventana del SYN-ACK
+	MSS        int     `json:"mss,omitempty"`
+	WScale     int     `json:"wscale,omitempty"`
+	Options    string  `json:"options,omitempty"` // orden de opciones TCP: "M,S,T,N,W"
+}
+
+// SNMPInfo valores crudos del grupo system (RFC 1213) y versión que respondió
+type SNMPInfo struct {
+	Version     string `json:"version"` // v1, v2c o v3
+	SysName     string `json:"sys_name,omitempty"`
+	SysDescr    string `json:"sys_descr,omitempty"`
+	SysObjectID string `json:"sys_object_id,omitempty"`
+}
+
+// UPnPInfo datos de la descripción XML que anuncia el equipo por SSDP
+type UPnPInfo struct {
+	FriendlyName string `json:"friendly_name,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	ModelName    string `json:"model_name,omitempty"`
+	ModelNumber  string `json:"model_number,omitempty"`
+	SerialNumber string `json:"serial_number,omitempty"`
+	DeviceType   string `json:"device_type,omitempty"` // urn:schemas-upnp-org:device:...
+}
+
+// HTTPInfo huella de la interfaz web de un puerto
+type HTTPInfo struct {
+	Port        int    `json:"port"`
+	Scheme      string `json:"scheme"` // http o https
+	Status      int    `json:"status"`
+	URL         string `json:"url,omitempty"` // URL final si hubo redirecciones
+	Title       string `json:"title,omitempty"`
+	Server      string `json:"server,omitempty"`
+	Realm       string `json:"realm,omitempty"`        // WWW-Authenticate (suele traer el modelo)
+	Generator   string `json:"generator,omitempty"`    // <meta name="generator">
+	FaviconHash int32  `json:"favicon_hash,omitempty"` // mmh3 del favicon, compatible con Shodan
+}
+
+// RTSPInfo respuesta a OPTIONS/DESCRIBE de un puerto RTSP (cámaras, NVR)
+type RTSPInfo struct {
+	Port    int      `json:"port"`
+	Status  int      `json:"status"` // del DESCRIBE (401 = pide credenciales)
+	Server  string   `json:"server,omitempty"`
+	Realm   string   `json:"realm,omitempty"`   // WWW-Authenticate (suele traer modelo o serie)
+	Session string   `json:"session,omitempty"` // SDP s= / i=
+	Tool    string   `json:"tool,omitempty"`    // SDP a=tool
+	Media   []string `json:"media,omitempty"`   // "video H264", "audio PCMA"...
+	Vendor  string   `json:"vendor,omitempty"`  // fabricante deducido de Server/realm/SDP
+	Model   string   `json:"model,omitempty"`
+}
+
+// SSHInfo identificación SSH de un puerto y lo que se deduce de ella
+type SSHInfo struct {
+	Port     int    `json:"port"`
+	Banner   string `json:"banner"`             // "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6"
+	Protocol string `json:"protocol,omitempty"` // 2.0, 1.99
+	Product  string `json:"product,omitempty"`  // OpenSSH, dropbear, ROSSSH
+	Version  string `json:"version,omitempty"`
+	Comment  string `json:"comment,omitempty"` // lo que sigue al software ("Ubuntu-3ubuntu0.6")
+	OS       string `json:"os,omitempty"`      // Linux, FreeBSD, Windows, RouterOS, Cisco IOS...
+	Distro   string `json:"distro,omitempty"`  // Ubuntu, Debian, Raspberry Pi OS, MikroTik
+	Release  string `json:"release,omitempty"` // 22.04, 12
+	Family   string `json:"family,omitempty"`  // router, network o embedded (Dropbear)
+}
+
+// TLSCert certificado que presenta un puerto TLS (sin validar la cadena)
+type TLSCert struct {
+	Port        int       `json:"port"`
+	Subject     string    `json:"subject,omitempty"` // CN del sujeto
+	SANs        []string  `json:"sans,omitempty"`    // nombres DNS e IPs alternativos
+	Issuer      string    `json:"issuer,omitempty"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	KeyType     string    `json:"key_type,omitempty"` // "RSA 2048", "ECDSA P-256"...
+	SelfSigned  bool      `json:"self_signed,omitempty"`
+	Expired     bool      `json:"expired,omitempty"`
+	ExpiresSoon bool      `json:"expires_soon,omitempty"` // vence en menos de 30 días
+}
+
+// Service servicio identificado en un puerto por las sondas de servicio
+type Service struct {
+	Port       int    `json:"port"`
+	Protocol   string `json:"protocol"`
+	Name       string `json:"name"`
+	Product    string `json:"product,omitempty"`
+	Version    string `json:"version,omitempty"`
+	Info       string `json:"info,omitempty"`
 	DeviceType string `json:"device_type,omitempty"`
 }
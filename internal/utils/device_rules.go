@@ -0,0 +1,324 @@
+package scan
+
+import (
+	_ "embed"
+	"encoding/json"
+	"escaner/internal/models"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ----------------------- clasificador de dispositivos por reglas -------------------------
+
+// reglas por defecto, embebidas en el binario
+//
+//go:embed data/device_rules.json
+var defaultDeviceRulesJSON []byte
+
+// DeviceRule asigna DeviceType cuando se cumplen todas las condiciones de Match.
+// Gana la regla que matchea con mayor Priority (a igual prioridad, la primera).
+type DeviceRule struct {
+	Name       string    `json:"name"`
+	DeviceType string    `json:"device_type"`
+	Priority   int       `json:"priority"`
+	Disabled   bool      `json:"disabled,omitempty"` // para apagar una regla por defecto desde un override
+	Match      RuleMatch `json:"match"`
+}
+
+// RuleMatch condiciones de una regla; las vacías no se evalúan. Las de texto son regex.
+//   - ports_any / ports_all: puertos TCP abiertos
+//   - banner: banner leído de los puertos de ports_any (o de los abiertos conocidos)
+//   - http / http_title / http_server: "Server título realm generator", título o
+//     header Server de algún puerto web abierto de ports_any (80/8080/8000/443/8443
+//     si no se indica), por HTTP o HTTPS
+//   - vendor: fabricante por OUI de la MAC
+//   - reverse_dns: nombre PTR
+//   - service: "nombre producto versión" de cada servicio detectado
+//   - mdns: tipos DNS-SD y modelo anunciados por mDNS ("_ipp._tcp _http._tcp HP LaserJet")
+//   - snmp: "sysDescr sysObjectID" leídos por SNMP ("RouterOS RB750 1.3.6.1.4.1.14988.1")
+//   - upnp: "deviceType fabricante modelo nombre" de la descripción UPnP
+//   - rtsp: "RTSP/1.0 estado Server realm sesión tool fabricante modelo medios" de
+//     algún puerto RTSP abierto de ports_any (554/8554 si no se indica) que respondió
+//     a OPTIONS/DESCRIBE
+//   - rtsp_camera: ese mismo puerto RTSP muestra algo propio de una cámara (ver
+//     rtspIsCamera); sin esto un RTSP cualquiera puede ser un servidor de medios
+//   - os / os_confidence: familia de sistema de la huella pasiva (Windows, Linux,
+//     BSD/macOS, Embedded RTOS, Network OS) y confianza mínima para considerarla
+//   - ssh: "producto versión sistema distribución release familia" deducido de la
+//     identificación SSH de algún puerto de ports_any (22/2222 si no se indica)
+type RuleMatch struct {
+	PortsAny     []int   `json:"ports_any,omitempty"`
+	PortsAll     []int   `json:"ports_all,omitempty"`
+	Banner       string  `json:"banner,omitempty"`
+	HTTP         string  `json:"http,omitempty"`
+	HTTPTitle    string  `json:"http_title,omitempty"`
+	HTTPServer   string  `json:"http_server,omitempty"`
+	Vendor       string  `json:"vendor,omitempty"`
+	ReverseDNS   string  `json:"reverse_dns,omitempty"`
+	Service      string  `json:"service,omitempty"`
+	MDNS         string  `json:"mdns,omitempty"`
+	SNMP         string  `json:"snmp,omitempty"`
+	UPnP         string  `json:"upnp,omitempty"`
+	RTSP         string  `json:"rtsp,omitempty"`
+	RTSPCamera   bool    `json:"rtsp_camera,omitempty"`
+	SSH          string  `json:"ssh,omitempty"`
+	OS           string  `json:"os,omitempty"`
+	OSConfidence float64 `json:"os_confidence,omitempty"`
+
+	banner, http, httpTitle, httpServer, vendor, reverseDNS, service, mdns, snmp, upnp, rtsp, ssh, os *regexp.Regexp
+}
+
+type deviceRuleFile struct {
+	Rules []DeviceRule `json:"rules"`
+}
+
+// puertos web por defecto para las condiciones http
+var defaultWebPorts = []int{80, 8080, 8000, 443, 8443}
+
+var (
+	deviceRulesMu sync.RWMutex
+	deviceRules   []DeviceRule
+)
+
+func init() {
+	rules, err := parseDeviceRules(defaultDeviceRulesJSON)
+	if err != nil {
+		panic("device_rules.json embebido inválido: " + err.Error())
+	}
+	deviceRules = sortRules(rules)
+}
+
+// LoadDeviceRules carga reglas desde un archivo JSON (ver LoadDeviceRulesJSON)
+func LoadDeviceRules(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error leyendo reglas de dispositivos: %w", err)
+	}
+	if err := LoadDeviceRulesJSON(data); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadDeviceRulesJSON aplica un conjunto de reglas (de disco o enviado por el backend).
+// Una regla con el mismo nombre que una existente la reemplaza; las nuevas se agregan.
+func LoadDeviceRulesJSON(data []byte) error {
+	rules, err := parseDeviceRules(data)
+	if err != nil {
+		return err
+	}
+	deviceRulesMu.Lock()
+	defer deviceRulesMu.Unlock()
+	merged := slices.Clone(deviceRules)
+	for _, r := range rules {
+		i := slices.IndexFunc(merged, func(q DeviceRule) bool { return q.Name == r.Name })
+		if i >= 0 {
+			merged[i] = r
+		} else {
+			merged = append(merged, r)
+		}
+	}
+	deviceRules = sortRules(merged)
+	return nil
+}
+
+func parseDeviceRules(data []byte) ([]DeviceRule, error) {
+	var f deviceRuleFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("json de reglas inválido: %w", err)
+	}
+	for i := range f.Rules {
+		r := &f.Rules[i]
+		if r.Name == "" {
+			return nil, fmt.Errorf("regla #%d sin nombre", i+1)
+		}
+		if r.DeviceType == "" && !r.Disabled {
+			return nil, fmt.Errorf("regla %s sin device_type", r.Name)
+		}
+		m := &r.Match
+		for _, c := range []struct {
+			pattern string
+			re      **regexp.Regexp
+		}{
+			{m.Banner, &m.banner},
+			{m.HTTP, &m.http},
+			{m.HTTPTitle, &m.httpTitle},
+			{m.HTTPServer, &m.httpServer},
+			{m.Vendor, &m.vendor},
+			{m.ReverseDNS, &m.reverseDNS},
+			{m.Service, &m.service},
+			{m.MDNS, &m.mdns},
+			{m.SNMP, &m.snmp},
+			{m.UPnP, &m.upnp},
+			{m.RTSP, &m.rtsp},
+			{m.SSH, &m.ssh},
+			{m.OS, &m.os},
+		} {
+			if c.pattern == "" {
+				continue
+			}
+			re, err := regexp.Compile(c.pattern)
+			if err != nil {
+				return nil, fmt.Errorf("regla %s: patrón %q: %w", r.Name, c.pattern, err)
+			}
+			*c.re = re
+		}
+	}
+	return f.Rules, nil
+}
+
+// sortRules ordena por prioridad descendente conservando el orden del archivo
+func sortRules(rules []DeviceRule) []DeviceRule {
+	slices.SortStableFunc(rules, func(a, b DeviceRule) int { return b.Priority - a.Priority })
+	return rules
+}
+
+// deviceEvidence datos de un host que las reglas consultan. Las sondas de red
+// (puertos, banners, HTTP, RTSP, SSH) pasan por la sesión del host: solo se hacen cuando
+// una regla las pide y se reutilizan las que ya hizo el escaneo.
+type deviceEvidence struct {
+	sess       *hostSession
+	vendor     string
+	reverseDNS string
+	services   []models.Service
+	mdns       string
+	snmp       string
+	upnp       string
+	os         *models.OSGuess
+}
+
+// matches evalúa la regla: primero las condiciones sin red, luego puertos, banner, HTTP, RTSP y SSH
+func (r *DeviceRule) matches(e *deviceEvidence) bool {
+	m := &r.Match
+	if m.vendor != nil && !m.vendor.MatchString(e.vendor) {
+		return false
+	}
+	if m.reverseDNS != nil && !m.reverseDNS.MatchString(e.reverseDNS) {
+		return false
+	}
+	if m.mdns != nil && !m.mdns.MatchString(e.mdns) {
+		return false
+	}
+	if m.snmp != nil && !m.snmp.MatchString(e.snmp) {
+		return false
+	}
+	if m.upnp != nil && !m.upnp.MatchString(e.upnp) {
+		return false
+	}
+	if m.os != nil && (e.os == nil || e.os.Confidence < m.OSConfidence || !m.os.MatchString(e.os.Family)) {
+		return false
+	}
+	if m.service != nil && !slices.ContainsFunc(e.services, func(s models.Service) bool {
+		return m.service.MatchString(strings.TrimSpace(s.Name + " " + s.Product + " " + s.Version))
+	}) {
+		return false
+	}
+	for _, p := range m.PortsAll {
+		if !e.sess.isOpen(p) {
+			return false
+		}
+	}
+	var openAny []int
+	for _, p := range m.PortsAny {
+		if e.sess.isOpen(p) {
+			openAny = append(openAny, p)
+		}
+	}
+	if len(m.PortsAny) > 0 && len(openAny) == 0 {
+		return false
+	}
+
+	if m.banner != nil {
+		candidates := openAny
+		if len(m.PortsAny) == 0 {
+			candidates = e.sess.knownOpen()
+		}
+		if !slices.ContainsFunc(candidates, func(p int) bool { return m.banner.MatchString(e.sess.banner(p)) }) {
+			return false
+		}
+	}
+
+	if m.http != nil || m.httpTitle != nil || m.httpServer != nil {
+		web := openAny
+		if len(m.PortsAny) == 0 {
+			for _, p := range defaultWebPorts {
+				if e.sess.isOpen(p) {
+					web = append(web, p)
+				}
+			}
+		}
+		if !slices.ContainsFunc(web, func(p int) bool {
+			info, ok := e.sess.httpInfo(p)
+			return ok && m.matchesHTTP(info)
+		}) {
+			return false
+		}
+	}
+
+	if m.rtsp != nil || m.RTSPCamera {
+		candidates := openAny
+		if len(m.PortsAny) == 0 {
+			candidates = rtspPorts
+		}
+		if !slices.ContainsFunc(candidates, func(p int) bool {
+			info, ok := e.sess.rtspInfo(p)
+			return ok && (m.rtsp == nil || m.rtsp.MatchString(rtspText(info))) &&
+				(!m.RTSPCamera || rtspIsCamera(info))
+		}) {
+			return false
+		}
+	}
+
+	if m.ssh != nil {
+		candidates := openAny
+		if len(m.PortsAny) == 0 {
+			candidates = sshPorts
+		}
+		if !slices.ContainsFunc(candidates, func(p int) bool {
+			info, ok := e.sess.sshInfo(p)
+			return ok && m.ssh.MatchString(sshText(info))
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *RuleMatch) matchesHTTP(info models.HTTPInfo) bool {
+	if m.http != nil && !m.http.MatchString(httpText(info)) {
+		return false
+	}
+	if m.httpTitle != nil && !m.httpTitle.MatchString(info.Title) {
+		return false
+	}
+	if m.httpServer != nil && !m.httpServer.MatchString(info.Server) {
+		return false
+	}
+	return true
+}
+
+// classifyDevice recorre las reglas por prioridad y devuelve el DeviceType de la
+// primera que matchea, o "Unknown"
+func classifyDevice(e *deviceEvidence) string {
+	deviceRulesMu.RLock()
+	rules := deviceRules
+	deviceRulesMu.RUnlock()
+
+	for i := range rules {
+		if e.sess.ctx.Err() != nil {
+			break
+		}
+		r := &rules[i]
+		if r.Disabled {
+			continue
+		}
+		if r.matches(e) {
+			return r.DeviceType
+		}
+	}
+	return "Unknown"
+}
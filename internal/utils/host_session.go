@@ -0,0 +1,344 @@
+package scan
+
+import (
+	"context"
+	"escaner/internal/models"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ----------------------- sesión de sondeo por host -------------------------
+
+// hostSession memoriza lo que ya se sondeó de un host durante su escaneo: estado
+// de cada puerto TCP y UDP, respuestas a payloads (banners, datagramas), GET HTTP,
+// certificados TLS y respuestas RTSP. Así el barrido de
+// puertos, las sondas de servicio, las reglas de dispositivo y enrichName
+// comparten resultados y cada puerto se conecta una sola vez por tipo de sonda.
+// Es segura para usar desde varias goroutines; dos pedidos simultáneos de la
+// misma sonda esperan a una única conexión. Cada conexión real respeta throttle
+// y ocupa un lugar de dials mientras dura.
+type hostSession struct {
+	ctx      context.Context
+	ip       string
+	timeout  time.Duration
+	retries  int // reintentos de pings, conexiones TCP y sondas UDP sin respuesta
+	throttle *scanThrottle
+	dials    dialLimiter
+
+	// timeouts según el RTT medido del host (ver rtt_utils.go)
+	adaptive   bool
+	maxTimeout time.Duration
+	rtt        rttEstimator
+
+	open       memo[int, bool]
+	replies    memo[exchangeKey, string]
+	http       memo[int, httpResult]
+	udpOpen    memo[int, bool]
+	udpReplies memo[exchangeKey, string]
+	tls        memo[int, tlsResult]
+	rtsp       memo[int, rtspResult]
+}
+
+type rtspResult struct {
+	info models.RTSPInfo
+	ok   bool
+}
+
+type tlsResult struct {
+	cert models.TLSCert
+	ok   bool
+}
+
+type exchangeKey struct {
+	port    int
+	payload string
+}
+
+type httpResult struct {
+	info models.HTTPInfo
+	ok   bool
+}
+
+func newHostSession(ctx context.Context, ip string, opts ScanOptions, throttle *scanThrottle, dials dialLimiter) *hostSession {
+	s := &hostSession{
+		ctx:        ctx,
+		ip:         ip,
+		timeout:    opts.Timeout,
+		retries:    opts.Retries,
+		throttle:   throttle,
+		dials:      dials,
+		adaptive:   opts.AdaptiveTimeout,
+		maxTimeout: opts.MaxTimeout,
+	}
+	if s.maxTimeout < s.timeout {
+		s.maxTimeout = 4 * s.timeout
+	}
+	return s
+}
+
+// start reserva un lugar entre las conexiones en curso del escaneo y espera el
+// turno de throttle; si devuelve true hay que liberar el lugar con s.dials.release
+func (s *hostSession) start() bool {
+	if !s.dials.acquire(s.ctx) {
+		return false
+	}
+	if s.throttle.wait(s.ctx, s.ip) != nil {
+		s.dials.release()
+		return false
+	}
+	return true
+}
+
+// reintentos de una conexión que falló por falta de recursos locales; no
+// cuentan como intentos porque el paquete nunca salió
+const (
+	resourceRetries = 6
+	resourceBackoff = 50 * time.Millisecond
+)
+
+// isOpen conecta al puerto TCP la primera vez y recuerda el resultado. Solo
+// reintenta si no hubo respuesta: un RST ya dice que el puerto está cerrado.
+// Si faltan descriptores o buffers espera y vuelve a probar en vez de darlo
+// por cerrado.
+func (s *hostSession) isOpen(port int) bool {
+	return s.open.get(port, func() bool {
+		backoff := resourceBackoff
+		for attempt, starved := 0, 0; ; attempt++ {
+			if !s.start() {
+				return false
+			}
+			start := time.Now()
+			err := dialTCP(s.ctx, s.ip, port, s.connectTimeout(attempt))
+			s.dials.release()
+			if isResourceErr(err) && starved < resourceRetries {
+				starved++
+				attempt--
+				select {
+				case <-s.ctx.Done():
+					return false
+				case <-time.After(backoff):
+				}
+				backoff *= 2
+				continue
+			}
+			// el RST también es una ida y vuelta: sirve de muestra aunque el host no responda ping
+			refused := isConnRefused(err)
+			if err == nil || refused {
+				s.rtt.observe(time.Since(start))
+			}
+			if err == nil {
+				return true
+			}
+			if attempt >= s.retries || refused {
+				return false
+			}
+		}
+	})
+}
+
+// openPorts prueba los puertos TCP en paralelo (hostPortWorkers a la vez) y
+// devuelve los abiertos en orden ascendente
+func (s *hostSession) openPorts(ports []int) []int {
+	return s.probePorts(ports, s.isOpen)
+}
+
+// openUDPPorts igual que openPorts para los puertos UDP que respondieron
+func (s *hostSession) openUDPPorts(ports []int) []int {
+	return s.probePorts(ports, s.isUDPOpen)
+}
+
+func (s *hostSession) probePorts(ports []int, probe func(int) bool) []int {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var open []int
+	sem := make(chan struct{}, hostPortWorkers)
+
+	for _, p := range ports {
+		if s.ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if probe(p) {
+				mu.Lock()
+				open = append(open, p)
+				mu.Unlock()
+			}
+		}(p)
+	}
+	wg.Wait()
+	slices.Sort(open)
+	return open
+}
+
+// knownOpen puertos que ya se comprobó que están abiertos, en orden ascendente
+func (s *hostSession) knownOpen() []int {
+	var out []int
+	s.open.each(func(p int, open bool) {
+		if open {
+			out = append(out, p)
+		}
+	})
+	slices.Sort(out)
+	return out
+}
+
+// exchange envía payload (latin-1, vacío = solo leer banner) y devuelve la respuesta.
+// No conecta a puertos que ya se saben cerrados.
+func (s *hostSession) exchange(port int, payload string) string {
+	if !s.isOpen(port) {
+		return ""
+	}
+	return s.replies.get(exchangeKey{port, payload}, func() string {
+		if !s.start() {
+			return ""
+		}
+		defer s.dials.release()
+		return probeExchange(s.ctx, s.ip, port, payload, s.probeTimeout(2))
+	})
+}
+
+// banner lo que envía el servidor al conectar (SSH, FTP, SMTP...)
+func (s *hostSession) banner(port int) string {
+	return s.exchange(port, "")
+}
+
+// httpInfo huella HTTP/HTTPS de GET / en el puerto (ver httpFingerprint)
+func (s *hostSession) httpInfo(port int) (models.HTTPInfo, bool) {
+	if !s.isOpen(port) {
+		return models.HTTPInfo{}, false
+	}
+	r := s.http.get(port, func() httpResult {
+		if !s.start() {
+			return httpResult{}
+		}
+		defer s.dials.release()
+		info, ok := httpFingerprint(s.ctx, s.ip, port, s.probeTimeout(4))
+		return httpResult{info, ok}
+	})
+	return r.info, r.ok
+}
+
+// tlsCert certificado que presenta el puerto en el handshake TLS
+func (s *hostSession) tlsCert(port int) (models.TLSCert, bool) {
+	if !s.isOpen(port) {
+		return models.TLSCert{}, false
+	}
+	r := s.tls.get(port, func() tlsResult {
+		if !s.start() {
+			return tlsResult{}
+		}
+		defer s.dials.release()
+		cert, ok := probeTLSCert(s.ctx, s.ip, port, s.probeTimeout(3))
+		return tlsResult{cert, ok}
+	})
+	return r.cert, r.ok
+}
+
+// sshInfo identificación del servidor SSH del puerto (ver parseSSHBanner)
+func (s *hostSession) sshInfo(port int) (models.SSHInfo, bool) {
+	return parseSSHBanner(port, s.banner(port))
+}
+
+// rtspInfo respuesta del puerto a OPTIONS/DESCRIBE (ver rtspProbe)
+func (s *hostSession) rtspInfo(port int) (models.RTSPInfo, bool) {
+	if !s.isOpen(port) {
+		return models.RTSPInfo{}, false
+	}
+	r := s.rtsp.get(port, func() rtspResult {
+		if !s.start() {
+			return rtspResult{}
+		}
+		defer s.dials.release()
+		info, ok := rtspProbe(s.ctx, s.ip, port, s.probeTimeout(3))
+		return rtspResult{info, ok}
+	})
+	return r.info, r.ok
+}
+
+// isUDPOpen envía en paralelo las sondas UDP del puerto (ver service_probes.json)
+// y recuerda si alguna recibió una respuesta válida. Un puerto UDP sin respuesta
+// puede estar cerrado o filtrado: solo una respuesta reconocida cuenta.
+func (s *hostSession) isUDPOpen(port int) bool {
+	return s.udpOpen.get(port, func() bool {
+		var wg sync.WaitGroup
+		for _, p := range probesForPort("udp", port) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.udpExchange(port, p.Payload)
+			}()
+		}
+		wg.Wait()
+		_, ok := detectService(s, "udp", port)
+		return ok
+	})
+}
+
+// udpExchange envía payload (latin-1) por UDP y devuelve la respuesta; un
+// datagrama sin respuesta se reenvía hasta retries veces
+func (s *hostSession) udpExchange(port int, payload string) string {
+	return s.udpReplies.get(exchangeKey{port, payload}, func() string {
+		for attempt := 0; attempt <= s.retries; attempt++ {
+			if !s.start() {
+				break
+			}
+			r := probeUDPExchange(s.ctx, s.ip, port, payload, s.probeTimeout(1))
+			s.dials.release()
+			if r != "" {
+				return r
+			}
+		}
+		return ""
+	})
+}
+
+// memo caché concurrente: la primera llamada con una clave ejecuta f y las
+// demás (simultáneas o posteriores) reciben el mismo valor
+type memo[K comparable, V any] struct {
+	mu sync.Mutex
+	m  map[K]*memoEntry[V]
+}
+
+type memoEntry[V any] struct {
+	done chan struct{}
+	v    V
+}
+
+func (c *memo[K, V]) get(key K, f func() V) V {
+	c.mu.Lock()
+	if c.m == nil {
+		c.m = map[K]*memoEntry[V]{}
+	}
+	e, ok := c.m[key]
+	if !ok {
+		e = &memoEntry[V]{done: make(chan struct{})}
+		c.m[key] = e
+	}
+	c.mu.Unlock()
+
+	if ok {
+		<-e.done
+		return e.v
+	}
+	e.v = f()
+	close(e.done)
+	return e.v
+}
+
+// each recorre los valores ya calculados (ignora los que están en curso)
+func (c *memo[K, V]) each(f func(K, V)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.m {
+		select {
+		case <-e.done:
+			f(k, e.v)
+		default:
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package scan
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"escaner/internal/models"
+	"html"
+	"io"
+	"math/bits"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ----------------------- huella HTTP / HTTPS -------------------------
+
+// puertos donde se busca una interfaz web
+var webPorts = []int{80, 443, 8080, 8443, 8000, 8008, 8081, 8888, 81, 5000, 5001, 4443, 9443}
+
+const (
+	maxHTTPRedirects = 5
+	maxHTTPBody      = 128 << 10
+	maxFavicon       = 200 << 10
+)
+
+// webTransport sin proxy ni keep-alive; HTTPS acepta cualquier certificado y TLS viejo
+var webTransport = &http.Transport{
+	Proxy:             nil,
+	TLSClientConfig:   tlsProbeConfig,
+	DisableKeepAlives: true,
+}
+
+var (
+	titleRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	metaRe  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	linkRe  = regexp.MustCompile(`(?is)<link\s[^>]*>`)
+	realmRe = regexp.MustCompile(`(?i)realm="([^"]*)"`)
+)
+
+// fingerprintWeb huella de cada puerto web abierto: los conocidos que están en
+// ports y los que las sondas de servicio identificaron como HTTP
+func fingerprintWeb(s *hostSession, ports []int, services []models.Service) []models.HTTPInfo {
+	var candidates []int
+	for _, p := range webPorts {
+		if slices.Contains(ports, p) {
+			candidates = append(candidates, p)
+		}
+	}
+	for _, svc := range services {
+		if svc.Protocol == "tcp" && strings.HasPrefix(svc.Name, "http") && !slices.Contains(candidates, svc.Port) {
+			candidates = append(candidates, svc.Port)
+		}
+	}
+	var out []models.HTTPInfo
+	for _, p := range candidates {
+		if info, ok := s.httpInfo(p); ok {
+			out = append(out, info)
+		}
+	}
+	return out
+}
+
+// httpFingerprint GET / en el puerto: primero por HTTPS si es un puerto TLS
+// conocido y si no por HTTP; si falla (o el servidor se queja de que habla el
+// otro protocolo) se prueba el otro esquema.
+func httpFingerprint(ctx context.Context, ip string, port int, timeout time.Duration) (models.HTTPInfo, bool) {
+	schemes := []string{"http", "https"}
+	if slices.Contains(tlsPorts, port) {
+		schemes = []string{"https", "http"}
+	}
+	for _, scheme := range schemes {
+		info, body, err := httpGetPage(ctx, ip, port, scheme, timeout)
+		if err != nil {
+			continue
+		}
+		if scheme == "http" && info.Status == http.StatusBadRequest && strings.Contains(strings.ToLower(body), "https") {
+			// nginx: "The plain HTTP request was sent to HTTPS port"
+			if secure, _, err := httpGetPage(ctx, ip, port, "https", timeout); err == nil {
+				return secure, true
+			}
+		}
+		return info, true
+	}
+	return models.HTTPInfo{}, false
+}
+
+// httpGetPage pide / siguiendo hasta maxHTTPRedirects redirecciones dentro del
+// mismo host y extrae título, Server, realm, generator y hash del favicon
+func httpGetPage(ctx context.Context, ip string, port int, scheme string, timeout time.Duration) (models.HTTPInfo, string, error) {
+	host := stripZone(ip)
+	client := &http.Client{
+		Transport: webTransport,
+		Timeout:   2 * timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// no salir del equipo (portales en la nube, nombres que no resolvemos)
+			if len(via) >= maxHTTPRedirects || stripZone(req.URL.Hostname()) != host {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+	// la zona IPv6 va escapada en la URL (fe80::1%25eth0)
+	start := scheme + "://" + net.JoinHostPort(strings.ReplaceAll(ip, "%", "%25"), strconv.Itoa(port)) + "/"
+	resp, err := httpGet(ctx, client, start)
+	if err != nil {
+		return models.HTTPInfo{}, "", err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxHTTPBody))
+	body := string(raw)
+
+	info := models.HTTPInfo{
+		Port:   port,
+		Scheme: scheme,
+		Status: resp.StatusCode,
+		Server: resp.Header.Get("Server"),
+	}
+	if final := resp.Request.URL.String(); final != start {
+		info.URL = final
+	}
+	if m := titleRe.FindStringSubmatch(body); m != nil {
+		info.Title = cleanHTMLText(m[1])
+	}
+	for _, h := range resp.Header.Values("WWW-Authenticate") {
+		if m := realmRe.FindStringSubmatch(h); m != nil {
+			info.Realm = strings.TrimSpace(m[1])
+			break
+		}
+	}
+	for _, tag := range metaRe.FindAllString(body, -1) {
+		if strings.EqualFold(htmlAttr(tag, "name"), "generator") {
+			info.Generator = cleanHTMLText(htmlAttr(tag, "content"))
+			break
+		}
+	}
+	info.FaviconHash = faviconHash(ctx, client, resp.Request.URL, body, host)
+	return info, body, nil
+}
+
+func httpGet(ctx context.Context, client *http.Client, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (scan)")
+	return client.Do(req)
+}
+
+// faviconHash hash del ícono declarado en <link rel="icon"> o de /favicon.ico
+func faviconHash(ctx context.Context, client *http.Client, page *url.URL, body, host string) int32 {
+	href := "/favicon.ico"
+	for _, tag := range linkRe.FindAllString(body, -1) {
+		if rel := strings.ToLower(htmlAttr(tag, "rel")); strings.Contains(rel, "icon") && htmlAttr(tag, "href") != "" {
+			href = htmlAttr(tag, "href")
+			break
+		}
+	}
+	ref, err := url.Parse(html.UnescapeString(href))
+	if err != nil {
+		return 0
+	}
+	u := page.ResolveReference(ref)
+	if stripZone(u.Hostname()) != host || (u.Scheme != "http" && u.Scheme != "https") {
+		return 0
+	}
+	resp, err := httpGet(ctx, client, u.String())
+	if err != nil {
+		return 0
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFavicon+1))
+	if err != nil || len(data) == 0 || len(data) > maxFavicon {
+		return 0
+	}
+	return faviconMMH3(data)
+}
+
+// faviconMMH3 MurmurHash3 (x86, 32 bits) del favicon en base64 con saltos cada
+// 76 caracteres, igual que http.favicon.hash de Shodan
+func faviconMMH3(data []byte) int32 {
+	enc := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(enc) > 76 {
+		b.WriteString(enc[:76])
+		b.WriteByte('\n')
+		enc = enc[76:]
+	}
+	b.WriteString(enc)
+	b.WriteByte('\n')
+	return int32(murmur3([]byte(b.String())))
+}
+
+func murmur3(data []byte) uint32 {
+	const c1, c2 = 0xcc9e2d51, 0x1b873593
+	var h uint32
+	n := len(data)
+	for ; len(data) >= 4; data = data[4:] {
+		k := binary.LittleEndian.Uint32(data)
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+	var k uint32
+	switch len(data) {
+	case 3:
+		k ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(data[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+	h ^= uint32(n)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// htmlAttr valor de un atributo de una etiqueta (con comillas dobles, simples o sin comillas)
+func htmlAttr(tag, name string) string {
+	re := regexp.MustCompile(`(?is)\s` + regexp.QuoteMeta(name) + `\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	m := re.FindStringSubmatch(tag)
+	if m == nil {
+		return ""
+	}
+	return m[1] + m[2] + m[3]
+}
+
+// cleanHTMLText decodifica entidades y colapsa espacios
+func cleanHTMLText(s string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
+
+// httpText "Server título realm generator": el texto que miran las reglas http
+func httpText(info models.HTTPInfo) string {
+	return strings.Join(strings.Fields(strings.Join([]string{info.Server, info.Title, info.Realm, info.Generator}, " ")), " ")
+}
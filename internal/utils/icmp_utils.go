@@ -0,0 +1,202 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// errICMPUnavailable indica que no se pudo abrir ningún socket ICMP
+// (sin permisos o plataforma sin soporte) y hay que usar el comando ping.
+var errICMPUnavailable = errors.New("socket ICMP no disponible")
+
+// pingReply datos de una respuesta ICMP echo
+type pingReply struct {
+	RTT time.Duration
+	TTL int // TTL (IPv4) o hop limit (IPv6)
+}
+
+// icmpSocket describe un tipo de socket ICMP a intentar, en orden de preferencia.
+// raw=false es el socket de datagrama sin privilegios (Linux con
+// net.ipv4.ping_group_range, macOS); raw=true requiere root / CAP_NET_RAW / admin.
+type icmpSocket struct {
+	network string
+	address string
+	raw     bool
+}
+
+var icmpSockets4 = []icmpSocket{
+	{"udp4", "0.0.0.0", false},
+	{"ip4:icmp", "0.0.0.0", true},
+}
+
+var icmpSockets6 = []icmpSocket{
+	{"udp6", "::", false},
+	{"ip6:ipv6-icmp", "::", true},
+}
+
+var (
+	icmpID  = os.Getpid() & 0xffff
+	icmpSeq atomic.Uint32
+)
+
+// listenICMP abre el primer socket ICMP disponible de la lista.
+func listenICMP(sockets []icmpSocket) (*icmp.PacketConn, bool, error) {
+	var lastErr error
+	for _, s := range sockets {
+		c, err := icmp.ListenPacket(s.network, s.address)
+		if err == nil {
+			return c, s.raw, nil
+		}
+		lastErr = err
+	}
+	return nil, false, fmt.Errorf("%w: %v", errICMPUnavailable, lastErr)
+}
+
+// icmpEcho envía un echo request a ip y espera la respuesta sin lanzar procesos.
+// Devuelve RTT y TTL de la respuesta; errICMPUnavailable si no hay socket usable.
+func icmpEcho(ctx context.Context, ip string, timeout time.Duration) (pingReply, error) {
+	dst, err := netip.ParseAddr(ip)
+	if err != nil {
+		return pingReply{}, fmt.Errorf("%w: %v", errICMPUnavailable, err)
+	}
+	dst = dst.Unmap()
+
+	var reply pingReply
+	err = icmpExchange(ctx, dst, timeout, func(peer netip.Addr, r pingReply) bool {
+		if peer.WithZone("") != dst.WithZone("") {
+			return false
+		}
+		reply = r
+		return true
+	})
+	return reply, err
+}
+
+// icmpExchange envía un echo request a dst y entrega cada echo reply que le
+// corresponde a onReply, hasta que onReply devuelva true, venza timeout o se
+// cancele ctx. Con dst multicast (ff02::1) sirve para recolectar varios vecinos.
+func icmpExchange(ctx context.Context, dst netip.Addr, timeout time.Duration, onReply func(peer netip.Addr, r pingReply) bool) error {
+	sockets, proto := icmpSockets4, 1
+	echoType, replyType := icmp.Type(ipv4.ICMPTypeEcho), icmp.Type(ipv4.ICMPTypeEchoReply)
+	if dst.Is6() {
+		sockets, proto = icmpSockets6, 58
+		echoType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	conn, raw, err := listenICMP(sockets)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	// pedir el TTL / hop limit de la respuesta como mensaje de control (no soportado
+	// en Windows: ahí queda en 0 y tryPing lo completa con el comando ping)
+	var read func(b []byte) (int, int, net.Addr, error)
+	if dst.Is4() {
+		p := conn.IPv4PacketConn()
+		_ = p.SetControlMessage(ipv4.FlagTTL, true)
+		read = func(b []byte) (int, int, net.Addr, error) {
+			n, cm, peer, err := p.ReadFrom(b)
+			if cm != nil {
+				return n, cm.TTL, peer, err
+			}
+			return n, 0, peer, err
+		}
+	} else {
+		p := conn.IPv6PacketConn()
+		_ = p.SetControlMessage(ipv6.FlagHopLimit, true)
+		read = func(b []byte) (int, int, net.Addr, error) {
+			n, cm, peer, err := p.ReadFrom(b)
+			if cm != nil {
+				return n, cm.HopLimit, peer, err
+			}
+			return n, 0, peer, err
+		}
+	}
+
+	seq := int(icmpSeq.Add(1) & 0xffff)
+	msg := icmp.Message{
+		Type: echoType,
+		Code: 0,
+		Body: &icmp.Echo{ID: icmpID, Seq: seq, Data: []byte("escaner-ping")},
+	}
+	// en IPv6 el kernel calcula el checksum
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return err
+	}
+
+	var dstAddr net.Addr = &net.IPAddr{IP: dst.AsSlice(), Zone: dst.Zone()}
+	if !raw {
+		dstAddr = &net.UDPAddr{IP: dst.AsSlice(), Zone: dst.Zone()}
+	}
+
+	start := time.Now()
+	if _, err := conn.WriteTo(b, dstAddr); err != nil {
+		return err
+	}
+	if err := conn.SetReadDeadline(start.Add(timeout)); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, ttl, peer, err := read(buf)
+		if err != nil {
+			return err
+		}
+		rtt := time.Since(start)
+
+		m, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil || m.Type != replyType {
+			continue
+		}
+		echo, ok := m.Body.(*icmp.Echo)
+		// con socket de datagrama el kernel reescribe el ID, solo comparamos seq
+		if !ok || echo.Seq != seq || (raw && echo.ID != icmpID) {
+			continue
+		}
+		from, ok := addrFromNet(peer)
+		if !ok {
+			continue
+		}
+		if onReply(from, pingReply{RTT: rtt, TTL: ttl}) {
+			return nil
+		}
+	}
+}
+
+// addrFromNet convierte la dirección de origen de un paquete a netip.Addr (con zona)
+func addrFromNet(addr net.Addr) (netip.Addr, bool) {
+	var ip net.IP
+	var zone string
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		ip, zone = a.IP, a.Zone
+	case *net.UDPAddr:
+		ip, zone = a.IP, a.Zone
+	default:
+		return netip.Addr{}, false
+	}
+	out, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return netip.Addr{}, false
+	}
+	out = out.Unmap()
+	if out.Is6() && zone != "" {
+		out = out.WithZone(zone)
+	}
+	return out, true
+}
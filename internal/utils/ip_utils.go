@@ -3,67 +3,57 @@ package scan
 import (
 	"bufio"
 	"context"
+	"errors"
+	"escaner/internal/models"
 	"fmt"
 	"net"
+	"net/netip"
 	"os/exec"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 )
 
-func ipsFromCIDR(ipnet *net.IPNet) []string {
-	ip := ipnet.IP.To4()
-	if ip == nil {
-		return nil
+// stripZone quita la zona de una IPv6 link-local (fe80::1%eth0 -> fe80::1)
+func stripZone(ip string) string {
+	if i := strings.IndexByte(ip, '%'); i >= 0 {
+		return ip[:i]
 	}
-	var ips []string
-	maskOnes, bits := ipnet.Mask.Size()
-	if maskOnes == bits {
-		return []string{ip.String()}
-	}
-	start := binaryIP(ip)
-	hosts := 1 << (bits - maskOnes)
-	for i := 0; i < hosts; i++ {
-		ips = append(ips, ipFromUint32(start+uint32(i)).String())
-	}
-	return ips
-}
-
-func binaryIP(ip net.IP) uint32 {
-	if ip == nil {
-		return 0
-	}
-	ip4 := ip.To4()
-	if ip4 == nil {
-		return 0
-	}
-	return uint32(ip4[0])<<24 | uint32(ip4[1])<<16 | uint32(ip4[2])<<8 | uint32(ip4[3])
+	return ip
 }
 
-func ipFromUint32(u uint32) net.IP {
-	return net.IPv4(byte(u>>24), byte(u>>16), byte(u>>8), byte(u)).To4()
-}
+// ----------------------- puertos y scanning -------------------------
 
-func ipsFromRange(a, b net.IP) ([]string, error) {
-	ai := binaryIP(a.To4())
-	bi := binaryIP(b.To4())
-	if ai == 0 || bi == 0 {
-		return nil, fmt.Errorf("solo IPv4 soportado")
-	}
-	if ai > bi {
-		ai, bi = bi, ai
+// tryPing hace un ICMP echo nativo y devuelve RTT/TTL de la respuesta.
+// Si no se puede abrir ningún socket ICMP, o el socket no entrega el TTL (Windows
+// no soporta ese mensaje de control), recurre al comando ping del sistema.
+func tryPing(ctx context.Context, ip string, timeout time.Duration) (pingReply, bool) {
+	reply, err := icmpEcho(ctx, ip, timeout)
+	if err == nil {
+		// sin TTL la huella de SO queda vacía: se lo pedimos al ping del sistema
+		if reply.TTL == 0 {
+			if r, ok := pingExec(ctx, ip, timeout); ok && r.TTL > 0 {
+				reply.TTL = r.TTL
+			}
+		}
+		return reply, true
 	}
-	var out []string
-	for v := ai; v <= bi; v++ {
-		out = append(out, ipFromUint32(v).String())
+	if !errors.Is(err, errICMPUnavailable) {
+		return pingReply{}, false
 	}
-	return out, nil
+	return pingExec(ctx, ip, timeout)
 }
 
-// ----------------------- puertos y scanning -------------------------
+var (
+	rePingTTL  = regexp.MustCompile(`(?i)ttl[=:](\d+)`)
+	rePingTime = regexp.MustCompile(`(?i)(?:time|tiempo)[=<]([\d.]+)\s*ms`)
+)
 
-func tryPing(ip string, timeout time.Duration) bool {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+// pingExec último recurso: lanza el comando ping y parsea TTL/tiempo de la salida
+func pingExec(ctx context.Context, ip string, timeout time.Duration) (pingReply, bool) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	var cmd *exec.Cmd
@@ -74,19 +64,32 @@ func tryPing(ip string, timeout time.Duration) bool {
 		// en unix ping -c 1 (no usamos -W porque no está estandarizado en todos los sistemas)
 		cmd = exec.CommandContext(ctx, "ping", "-c", "1", ip)
 	}
-	err := cmd.Run()
-	return err == nil
+	out, err := cmd.Output()
+	if err != nil {
+		return pingReply{}, false
+	}
+
+	var reply pingReply
+	if m := rePingTTL.FindSubmatch(out); m != nil {
+		reply.TTL, _ = strconv.Atoi(string(m[1]))
+	}
+	if m := rePingTime.FindSubmatch(out); m != nil {
+		if ms, err := strconv.ParseFloat(string(m[1]), 64); err == nil {
+			reply.RTT = time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	return reply, true
 }
 
-func tryTCP(ip string, port int, timeout time.Duration) bool {
-	addr := fmt.Sprintf("%s:%d", ip, port)
+// dialTCP intenta conectar al puerto; nil si está abierto
+func dialTCP(ctx context.Context, ip string, port int, timeout time.Duration) error {
+	addr := net.JoinHostPort(ip, strconv.Itoa(port))
 	d := net.Dialer{Timeout: timeout}
-	conn, err := d.Dial("tcp", addr)
+	conn, err := d.DialContext(ctx, "tcp", addr)
 	if err != nil {
-		return false
+		return err
 	}
-	_ = conn.Close()
-	return true
+	return conn.Close()
 }
 
 // ----------------------- Device fingerprint heuristics -------------------------
@@ -168,88 +171,43 @@ func tryTCP(ip string, port int, timeout time.Duration) bool {
 
 //		return "Unknown"
 //	}
-func detectDeviceType(ip string, ports []int, timeout time.Duration, mac string, reverseDNS string) string {
-	// 1) si ya es impresora mantenlo (si tienes lógica de impresora en otro lado)
-	// ejemplo simple: si puerto 9100 o 631 detecta impresora rápidamente
-	if tryTCP(ip, 9100, timeout/2) || tryTCP(ip, 631, timeout/2) || tryTCP(ip, 515, timeout/2) {
-		return "Printer"
-	}
 
-	// 2) OUI heurística
-	vendor := simpleOUIVendor(mac)
-	if strings.Contains(strings.ToLower(vendor), "camera") || strings.Contains(strings.ToLower(vendor), "dahua") {
-		return "Camera"
-	}
-	if strings.Contains(strings.ToLower(vendor), "yealink") || strings.Contains(strings.ToLower(vendor), "polycom") {
-		return "VoIP phone"
-	}
-	if strings.ToLower(vendor) == "apple" {
-		// puede ser iPhone / Mac / iPad -> dejamos Unknown y lo intentamos con mDNS/http
-	}
-
-	// 3) quick port probes + banner heuristics
-	// RTSP -> camera
-	if tryTCP(ip, 554, timeout/2) {
-		// intentar banner o DESCRIBE simple via TCP read
-		ban := bannerProbe(ip, 554, timeout/2)
-		if strings.Contains(strings.ToLower(ban), "rtsp") || strings.Contains(strings.ToLower(ban), "camera") {
-			return "Camera"
-		}
-		return "Camera"
-	}
-
-	// SIP/VoIP -> telefono IP
-	if tryTCP(ip, 5060, timeout/2) || tryTCP(ip, 5061, timeout/2) {
-		return "VoIP phone"
-	}
-
-	// SMB/Netbios/RDP/SSH -> probablemente PC/Server
-	if tryTCP(ip, 445, timeout/2) || tryTCP(ip, 139, timeout/2) || tryTCP(ip, 3389, timeout/2) || tryTCP(ip, 22, timeout/2) {
-		return "PC"
-	}
+// detectDeviceType clasifica el host con las reglas de device_rules.json (ver
+// LoadDeviceRules). Lo ya sabido del escaneo (MAC, PTR, mDNS, SNMP, UPnP, huella del sistema, servicios y lo sondeado
+// en la sesión) se reutiliza; el resto se sondea solo si alguna regla lo necesita.
+func detectDeviceType(s *hostSession, res models.Result) string {
+	return classifyDevice(&deviceEvidence{
+		sess:       s,
+		vendor:     res.Vendor,
+		reverseDNS: res.ReverseDNS,
+		services:   res.Services,
+		mdns:       strings.TrimSpace(strings.Join(res.MDNSServices, " ") + " " + res.Model),
+		snmp:       snmpEvidence(res.SNMP),
+		upnp:       upnpEvidence(res.UPnP),
+		os:         res.OS,
+	})
+}
 
-	// HTTP: chequear título / server para hints (cámaras y algunos móviles exponen admin pages)
-	if tryTCP(ip, 80, timeout/2) || tryTCP(ip, 8080, timeout/2) || tryTCP(ip, 8000, timeout/2) {
-		server, title := httpProbeTitle(ip, 80, timeout/2)
-		lower := strings.ToLower(server + " " + title)
-		if strings.Contains(lower, "hikvision") || strings.Contains(lower, "dahua") || strings.Contains(lower, "axis") {
-			return "Camera"
-		}
-		if strings.Contains(lower, "android") || strings.Contains(lower, "iphone") || strings.Contains(lower, "apple") {
-			return "Mobile"
-		}
-		if strings.Contains(lower, "phone") || strings.Contains(lower, "sip") || strings.Contains(lower, "asterisk") {
-			return "VoIP phone"
-		}
-		// si no encontramos pistas, pero hay HTTP, puede ser PC/IoT -> marcar unknown para no false-positive
+func snmpEvidence(info *models.SNMPInfo) string {
+	if info == nil {
+		return ""
 	}
+	return strings.TrimSpace(info.SysDescr + " " + info.SysObjectID)
+}
 
-	// 4) heurística por MAC -> mobiles / hubs
-	if vendor == "Apple" {
-		// si tiene HTTP y nombre via reverseDNS probablemente es "Mobile" o "PC"
-		if reverseDNS != "" {
-			if strings.Contains(strings.ToLower(reverseDNS), "iphone") || strings.Contains(strings.ToLower(reverseDNS), "ipad") {
-				return "Mobile"
-			}
-			// macbook suele tener 'macbook' o 'mac' en reverse dns
-			if strings.Contains(strings.ToLower(reverseDNS), "mac") {
-				return "PC"
-			}
-		}
-		// si nada, devolver Mobile como hipótesis baja
-		return "Mobile"
+func upnpEvidence(info *models.UPnPInfo) string {
+	if info == nil {
+		return ""
 	}
-
-	// fallback
-	return "Unknown"
+	return strings.Join(strings.Fields(strings.Join([]string{info.DeviceType, info.Manufacturer, info.ModelName, info.FriendlyName}, " ")), " ")
 }
 
 // probeHTTPForHints intenta un HEAD/GET muy corto para obtener Server o title
-func probeHTTPForHints(ip string, timeout time.Duration) string {
+func probeHTTPForHints(ctx context.Context, ip string, timeout time.Duration) string {
 	try := func(port int) string {
-		addr := fmt.Sprintf("%s:%d", ip, port)
+		addr := net.JoinHostPort(ip, strconv.Itoa(port))
 		d := net.Dialer{Timeout: timeout}
-		conn, err := d.Dial("tcp", addr)
+		conn, err := d.DialContext(ctx, "tcp", addr)
 		if err != nil {
 			return ""
 		}
@@ -336,8 +294,12 @@ func looksLikePrinterName(s string) bool {
 	return false
 }
 
+// ipLess ordena IPv4 antes que IPv6 y cada familia numéricamente
 func ipLess(a, b string) bool {
-	ai := binaryIP(net.ParseIP(a).To4())
-	bi := binaryIP(net.ParseIP(b).To4())
-	return ai < bi
+	ai, errA := netip.ParseAddr(a)
+	bi, errB := netip.ParseAddr(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return ai.Unmap().Less(bi.Unmap())
 }
@@ -2,8 +2,8 @@ package scan
 
 import (
 	"bufio"
+	"context"
 	"errors"
-	"fmt"
 	"os"
 	"os/exec"
 	"runtime"
@@ -11,128 +11,115 @@ import (
 	"time"
 )
 
-// getMACSimple intenta obtener la MAC para una IP usando:
-// 1) ip neigh show <ip> (Linux) -> busca "lladdr"
-// 2) /proc/net/arp (Linux)
-// 3) arp -n <ip> o arp -a (fallback)
+// ActiveARP habilita el envío de ARP requests propios cuando la IP no aparece en la
+// tabla de vecinos (solo Linux, requiere CAP_NET_RAW; sin permisos se omite).
+var ActiveARP = true
+
+// getMAC intenta obtener la MAC para una IP usando:
+// 1) tabla de vecinos del kernel vía rtnetlink (Linux)
+// 2) ARP request propio en el segmento local (Linux, si ActiveARP)
+// 3) /proc/net/arp (Linux, si netlink no respondió)
+// 4) arp -n <ip> o arp -a <ip> (Windows / macOS)
+// Solo devuelve una MAC cuya entrada corresponde exactamente a ip.
 // Devuelve la MAC en minúsculas con ":" o error.
-func getMAC(ip string, timeout time.Duration) (string, error) {
-	// Forzar creación de entrada ARP (ping / arping)
-	ensureARPEntrySimple(ip, timeout)
+// Con ping primero hace un ping para poblar la tabla ARP; no hace falta si el
+// host ya recibió tráfico unicast del escaneo. El ping y el ARP activo respetan throttle.
+func getMAC(ctx context.Context, ip string, timeout time.Duration, throttle *scanThrottle, ping bool) (string, error) {
+	if ping {
+		if err := throttle.wait(ctx, ip); err != nil {
+			return "", err
+		}
+		ensureARPEntrySimple(ctx, ip, timeout)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 
-	// 1) ip neigh (Linux) — muy recomendable
 	if runtime.GOOS == "linux" {
-		if mac := macFromIPNeigh(ip); mac != "" {
+		// 1) netlink
+		if mac := macFromNeighborTable(ip); mac != "" {
 			return mac, nil
 		}
-		// 2) /proc/net/arp
+		// 2) ARP activo
+		if ActiveARP && throttle.wait(ctx, ip) == nil {
+			if mac := macFromARPRequest(ctx, ip, timeout); mac != "" {
+				return mac, nil
+			}
+		}
+		// 3) /proc/net/arp
 		if mac := readMACFromProcNetARP(ip); mac != "" {
 			return mac, nil
 		}
+		return "", errors.New("MAC no encontrada")
 	}
 
-	// 3) Fallback: comando arp (Windows / *nix)
-	if mac := macFromARPCommandSimple(ip); mac != "" {
+	// 4) Fallback: comando arp (Windows / *nix)
+	if mac := macFromARPCommandSimple(ctx, ip); mac != "" {
 		return mac, nil
 	}
 
 	return "", errors.New("MAC no encontrada")
 }
 
-// ensureARPEntrySimple hace ping y, si está instalado, arping para poblar ARP.
-func ensureARPEntrySimple(ip string, timeout time.Duration) {
-	// Ping (cross-platform)
-	pingTimeout := int(timeout.Seconds())
-	if pingTimeout < 1 {
-		pingTimeout = 1
-	}
-
-	if runtime.GOOS == "windows" {
-		// Windows: ping -n 1 -w <ms>
-		_ = exec.Command("ping", "-n", "1", "-w", fmt.Sprintf("%d", pingTimeout*1000), ip).Run()
-	} else {
-		// Unix: ping -c 1 -W <sec>
-		_ = exec.Command("ping", "-c", "1", "-W", fmt.Sprintf("%d", pingTimeout), ip).Run()
-	}
-
-	// intentar arping si existe (solo Linux normalmente)
-	if runtime.GOOS == "linux" {
-		if _, err := exec.LookPath("arping"); err == nil {
-			// arping -c 1 -w <seg>
-			_ = exec.Command("arping", "-c", "1", "-w", fmt.Sprintf("%d", pingTimeout), ip).Run()
-		}
-	}
-}
-
-// macFromIPNeigh usa `ip neigh show <ip>` y busca "lladdr <mac>"
-func macFromIPNeigh(ip string) string {
-	out, err := exec.Command("ip", "neigh", "show", ip).Output()
-	if err != nil || len(out) == 0 {
-		return ""
-	}
-	s := string(out)
-	// Ejemplo: "192.168.0.24 dev eth0 lladdr f8:63:d9:9b:93:a3 REACHABLE"
-	if idx := strings.Index(s, "lladdr "); idx != -1 {
-		rest := s[idx+7:]
-		fields := strings.Fields(rest)
-		if len(fields) > 0 && looksLikeMAC(fields[0]) {
-			return strings.ToLower(normalizeMAC(fields[0]))
-		}
-	}
-	// También puede venir sin 'lladdr' pero con 'REACHABLE' - buscamos token MAC
-	for _, token := range strings.Fields(s) {
-		if looksLikeMAC(token) {
-			return strings.ToLower(normalizeMAC(token))
-		}
-	}
-	return ""
+// ensureARPEntrySimple hace un ping para que el sistema pueble su tabla ARP.
+func ensureARPEntrySimple(ctx context.Context, ip string, timeout time.Duration) {
+	// Ping ICMP nativo (cae al comando ping solo si no hay socket ICMP)
+	_, _ = tryPing(ctx, ip, timeout)
 }
 
-// macFromARPCommandSimple intenta `arp -n <ip>` o `arp -a` y parsea salida minimal
-func macFromARPCommandSimple(ip string) string {
+// macFromARPCommandSimple intenta `arp -n <ip>` o `arp -a <ip>` y parsea salida minimal.
+// Solo mira la línea cuya IP coincide exactamente; nunca devuelve la MAC de otro host.
+func macFromARPCommandSimple(ctx context.Context, ip string) string {
 	var out []byte
-	var err error
 
 	if runtime.GOOS == "windows" {
-		out, err = exec.Command("arp", "-a", ip).CombinedOutput()
+		out, _ = exec.CommandContext(ctx, "arp", "-a", ip).CombinedOutput()
 	} else {
-		// en linux/mac intentamos `arp -n <ip>` o `arp -n`
-		out, err = exec.Command("arp", "-n", ip).CombinedOutput()
-		if err != nil || len(out) == 0 {
-			out, _ = exec.Command("arp", "-n").CombinedOutput()
-		}
+		// en mac/bsd `arp -n <ip>`
+		out, _ = exec.CommandContext(ctx, "arp", "-n", ip).CombinedOutput()
 	}
-	if err != nil && len(out) == 0 {
+	if len(out) == 0 {
 		return ""
 	}
-	text := string(out)
 
-	// Buscar token que parezca MAC en la línea que contenga la IP
-	lines := strings.Split(text, "\n")
-	for _, line := range lines {
-		if strings.Contains(line, ip) {
-			for _, tok := range strings.Fields(line) {
-				c := strings.Trim(tok, "[],()")
-				if looksLikeMAC(c) {
-					return strings.ToLower(normalizeMAC(c))
-				}
+	// Windows: "  192.168.1.1    00-11-22-33-44-55   dinámico"
+	// macOS:   "? (192.168.1.1) at 0:11:22:33:44:55 on en0 ifscope [ethernet]"
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		hasIP := false
+		for _, tok := range fields {
+			if strings.Trim(tok, "[],()") == ip {
+				hasIP = true
+				break
 			}
 		}
-	}
-
-	// Si no hay línea con la IP, buscar cualquier MAC en salida (fallback)
-	for _, tok := range strings.Fields(text) {
-		c := strings.Trim(tok, "[],()")
-		if looksLikeMAC(c) {
-			return strings.ToLower(normalizeMAC(c))
+		if !hasIP {
+			continue
+		}
+		for _, tok := range fields {
+			c := strings.Trim(tok, "[],()")
+			if looksLikeMAC(c) {
+				mac := normalizeMAC(c)
+				if mac == "00:00:00:00:00:00" || mac == "ff:ff:ff:ff:ff:ff" {
+					return ""
+				}
+				return mac
+			}
 		}
 	}
 	return ""
 }
 
-// normalizeMAC convierte formatos con "-" a ":" y limpia
+// normalizeMAC convierte formatos con "-" a ":" y completa octetos de un dígito
+// (macOS imprime "0:11:2:..." sin ceros a la izquierda)
 func normalizeMAC(s string) string {
-	return strings.ReplaceAll(strings.ToLower(s), "-", ":")
+	parts := strings.Split(strings.ReplaceAll(strings.ToLower(s), "-", ":"), ":")
+	for i, p := range parts {
+		if len(p) == 1 {
+			parts[i] = "0" + p
+		}
+	}
+	return strings.Join(parts, ":")
 }
 
 // looksLikeMAC revisa patrón xx:xx:xx:xx:xx:xx (hex)
@@ -144,7 +131,7 @@ func looksLikeMAC(s string) bool {
 		return false
 	}
 	for _, p := range parts {
-		if len(p) != 2 {
+		if len(p) != 1 && len(p) != 2 {
 			return false
 		}
 		for _, ch := range p {
@@ -155,6 +142,7 @@ func looksLikeMAC(s string) bool {
 	}
 	return true
 }
+
 func readMACFromProcNetARP(ip string) string {
 	f, err := os.Open("/proc/net/arp")
 	if err != nil {
@@ -0,0 +1,280 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/ipv4"
+)
+
+// ----------------------- descubrimiento mDNS / DNS-SD -------------------------
+
+// tipos DNS-SD que se consultan: impresoras, Apple (AirPlay, Mac, iPhone/iPad),
+// Chromecast/TVs, estaciones Linux (avahi) y equipos con SMB/HTTP
+var mdnsServiceTypes = []string{
+	"_airplay._tcp",
+	"_raop._tcp",
+	"_companion-link._tcp",
+	"_apple-mobdev2._tcp",
+	"_device-info._tcp",
+	"_ipp._tcp",
+	"_ipps._tcp",
+	"_printer._tcp",
+	"_pdl-datastream._tcp",
+	"_scanner._tcp",
+	"_googlecast._tcp",
+	"_spotify-connect._tcp",
+	"_hap._tcp",
+	"_workstation._tcp",
+	"_smb._tcp",
+	"_http._tcp",
+}
+
+// claves TXT que traen el modelo, en orden de preferencia
+var mdnsModelKeys = []string{"model", "md", "ty", "usb_MDL", "product"}
+
+var mdnsGroup4 = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// mdnsHost lo que un equipo anuncia por mDNS
+type mdnsHost struct {
+	Hostname string   // nombre .local sin el sufijo
+	Model    string   // modelo según los TXT (model=, md=, ty=...)
+	Services []string // tipos DNS-SD anunciados, ej. "_ipp._tcp"
+}
+
+// mdnsDiscovery fase de descubrimiento que corre en paralelo al escaneo;
+// lookup espera a que termine la ventana de escucha
+type mdnsDiscovery struct {
+	done  chan struct{}
+	hosts map[string]*mdnsHost // ip -> anuncios
+}
+
+// startMDNSDiscovery consulta los tipos de servicio comunes por multicast en cada
+// interfaz IPv4 y escucha respuestas durante window. Las consultas salen desde un
+// puerto efímero (legacy unicast, RFC 6762 §6.7): los equipos responden directo a
+// nuestro socket sin que tengamos que unirnos al grupo ni competir por el 5353.
+func startMDNSDiscovery(ctx context.Context, window time.Duration, throttle *scanThrottle) *mdnsDiscovery {
+	d := &mdnsDiscovery{done: make(chan struct{}), hosts: map[string]*mdnsHost{}}
+	go func() {
+		defer close(d.done)
+		d.run(ctx, window, throttle)
+	}()
+	return d
+}
+
+// lookup devuelve lo anunciado por ip (espera a que termine el descubrimiento)
+func (d *mdnsDiscovery) lookup(ctx context.Context, ip string) (mdnsHost, bool) {
+	if d == nil {
+		return mdnsHost{}, false
+	}
+	select {
+	case <-d.done:
+	case <-ctx.Done():
+		return mdnsHost{}, false
+	}
+	h, ok := d.hosts[stripZone(ip)]
+	if !ok {
+		return mdnsHost{}, false
+	}
+	return *h, true
+}
+
+func (d *mdnsDiscovery) run(ctx context.Context, window time.Duration, throttle *scanThrottle) {
+	query, err := mdnsQuery()
+	if err != nil {
+		return
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	pc := ipv4.NewPacketConn(conn)
+	_ = pc.SetMulticastTTL(255)
+	ifaces, _ := net.Interfaces()
+	sent := false
+	for i := range ifaces {
+		iface := &ifaces[i]
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		if throttle.wait(ctx, mdnsGroup4.IP.String()) != nil {
+			return
+		}
+		if pc.SetMulticastInterface(iface) != nil {
+			continue
+		}
+		if _, err := pc.WriteTo(query, nil, mdnsGroup4); err == nil {
+			sent = true
+		}
+	}
+	if !sent {
+		return
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(window))
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		src, ok := netip.AddrFromSlice(from.IP)
+		if !ok {
+			continue
+		}
+		d.parse(buf[:n], src.Unmap())
+	}
+}
+
+// mdnsQuery una consulta PTR por cada tipo de servicio
+func mdnsQuery() ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	for _, t := range mdnsServiceTypes {
+		name, err := dnsmessage.NewName(t + ".local.")
+		if err != nil {
+			return nil, err
+		}
+		if err := b.Question(dnsmessage.Question{Name: name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET}); err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+// parse reparte los registros de una respuesta entre las IPs anunciadas.
+// Un servicio se atribuye a la IP del host de su SRV si viene en la respuesta,
+// y si no a quien respondió.
+func (d *mdnsDiscovery) parse(msg []byte, src netip.Addr) {
+	var p dnsmessage.Parser
+	h, err := p.Start(msg)
+	if err != nil || !h.Response {
+		return
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return
+	}
+	var records []dnsmessage.Resource
+	for {
+		r, err := p.Answer()
+		if err != nil {
+			break
+		}
+		records = append(records, r)
+	}
+	_ = p.SkipAllAuthorities()
+	for {
+		r, err := p.Additional()
+		if err != nil {
+			break
+		}
+		records = append(records, r)
+	}
+
+	addrs := map[string][]netip.Addr{} // host.local. -> IPs
+	instances := map[string]bool{}     // instancias de servicio vistas
+	targets := map[string]string{}     // instancia -> host.local. (SRV)
+	txts := map[string][]string{}      // instancia -> TXT
+	for _, r := range records {
+		name := r.Header.Name.String()
+		switch b := r.Body.(type) {
+		case *dnsmessage.AResource:
+			addrs[name] = append(addrs[name], netip.AddrFrom4(b.A))
+		case *dnsmessage.AAAAResource:
+			addrs[name] = append(addrs[name], netip.AddrFrom16(b.AAAA))
+		case *dnsmessage.PTRResource:
+			instances[b.PTR.String()] = true
+		case *dnsmessage.SRVResource:
+			instances[name] = true
+			targets[name] = b.Target.String()
+		case *dnsmessage.TXTResource:
+			instances[name] = true
+			txts[name] = b.TXT
+		}
+	}
+
+	for host, ips := range addrs {
+		for _, ip := range ips {
+			d.host(ip).setHostname(host)
+		}
+	}
+	for inst := range instances {
+		svc := mdnsServiceType(inst)
+		if svc == "" {
+			continue
+		}
+		ips := []netip.Addr{src}
+		if t, ok := targets[inst]; ok && len(addrs[t]) > 0 {
+			ips = addrs[t]
+		}
+		for _, ip := range ips {
+			h := d.host(ip)
+			if !slices.Contains(h.Services, svc) {
+				h.Services = append(h.Services, svc)
+			}
+			if t, ok := targets[inst]; ok {
+				h.setHostname(t)
+			}
+			if h.Model == "" {
+				h.Model = mdnsModel(txts[inst])
+			}
+		}
+	}
+}
+
+func (d *mdnsDiscovery) host(ip netip.Addr) *mdnsHost {
+	key := ip.WithZone("").String()
+	h, ok := d.hosts[key]
+	if !ok {
+		h = &mdnsHost{}
+		d.hosts[key] = h
+	}
+	return h
+}
+
+func (h *mdnsHost) setHostname(name string) {
+	if h.Hostname == "" && strings.HasSuffix(name, ".local.") {
+		h.Hostname = strings.TrimSuffix(name, ".local.")
+	}
+}
+
+// mdnsServiceType extrae el tipo de una instancia: "HP 4100._ipp._tcp.local." -> "_ipp._tcp"
+func mdnsServiceType(instance string) string {
+	i := strings.Index(instance, "._")
+	if i < 0 {
+		return ""
+	}
+	t := strings.TrimSuffix(instance[i+1:], ".local.")
+	if !strings.HasSuffix(t, "._tcp") && !strings.HasSuffix(t, "._udp") {
+		return ""
+	}
+	return t
+}
+
+// mdnsModel busca el modelo en los TXT ("model=MacBookPro18,3", "md=Chromecast"...)
+func mdnsModel(txt []string) string {
+	kv := map[string]string{}
+	for _, t := range txt {
+		if k, v, ok := strings.Cut(t, "="); ok {
+			kv[strings.ToLower(k)] = v
+		}
+	}
+	for _, k := range mdnsModelKeys {
+		if v := strings.Trim(kv[strings.ToLower(k)], "() "); v != "" {
+			return v
+		}
+	}
+	return ""
+}
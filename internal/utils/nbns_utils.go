@@ -0,0 +1,150 @@
+package scan
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// ----------------------- NetBIOS Name Service (NBSTAT) -------------------------
+
+// nbstatInfo datos de la tabla de nombres NetBIOS de un equipo (Windows, Samba)
+type nbstatInfo struct {
+	Name      string // nombre del equipo (<00> único)
+	Workgroup string // grupo de trabajo o dominio (<00> de grupo)
+	MAC       string // "unit ID" de la respuesta; Samba la envía en cero
+}
+
+// tipo NBSTAT y clase IN (RFC 1002 §4.2.17)
+const (
+	nbnsTypeNBSTAT = 0x0021
+	nbnsClassIN    = 0x0001
+	nbnsGroupFlag  = 0x8000
+)
+
+// nbstatQuery envía un NODE STATUS REQUEST al puerto UDP 137 y lee la tabla de nombres.
+// Solo IPv4: NetBIOS no existe sobre IPv6.
+func nbstatQuery(ctx context.Context, ip string, timeout time.Duration) (nbstatInfo, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !addr.Is4() {
+		return nbstatInfo{}, false
+	}
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "udp4", net.JoinHostPort(ip, "137"))
+	if err != nil {
+		return nbstatInfo{}, false
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	id := uint16(rand.N(0x10000))
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(nbstatRequest(id)); err != nil {
+		return nbstatInfo{}, false
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nbstatInfo{}, false
+		}
+		if info, err := parseNBSTAT(buf[:n], id); err == nil {
+			return info, true
+		}
+	}
+}
+
+// nbstatRequest consulta del nombre comodín "*" (NBSTAT no necesita conocer el nombre)
+func nbstatRequest(id uint16) []byte {
+	msg := make([]byte, 12, 50)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[4:], 1) // QDCOUNT
+	msg = append(msg, nbEncodeName("*")...)
+	msg = binary.BigEndian.AppendUint16(msg, nbnsTypeNBSTAT)
+	msg = binary.BigEndian.AppendUint16(msg, nbnsClassIN)
+	return msg
+}
+
+// nbEncodeName codificación "first level" de NetBIOS: el nombre relleno a 16
+// bytes y cada nibble convertido en una letra 'A'-'P'
+func nbEncodeName(name string) []byte {
+	raw := make([]byte, 16)
+	copy(raw, name)
+	out := []byte{32}
+	for _, c := range raw {
+		out = append(out, 'A'+(c>>4), 'A'+(c&0x0f))
+	}
+	return append(out, 0)
+}
+
+// parseNBSTAT interpreta la respuesta: nombre, tipo, clase, TTL, largo y luego
+// num_names entradas de 18 bytes (15 nombre + sufijo + flags) seguidas de la MAC
+func parseNBSTAT(msg []byte, id uint16) (nbstatInfo, error) {
+	if len(msg) < 12 || binary.BigEndian.Uint16(msg) != id || msg[2]&0x80 == 0 {
+		return nbstatInfo{}, fmt.Errorf("no es respuesta a la consulta")
+	}
+	if binary.BigEndian.Uint16(msg[6:]) == 0 {
+		return nbstatInfo{}, fmt.Errorf("respuesta sin registros")
+	}
+	off := 12
+	// nombre del registro: etiquetas o puntero de compresión
+	for off < len(msg) {
+		l := int(msg[off])
+		if l&0xc0 == 0xc0 {
+			off += 2
+			break
+		}
+		off++
+		if l == 0 {
+			break
+		}
+		off += l
+	}
+	if off+10 > len(msg) {
+		return nbstatInfo{}, fmt.Errorf("respuesta truncada")
+	}
+	if binary.BigEndian.Uint16(msg[off:]) != nbnsTypeNBSTAT {
+		return nbstatInfo{}, fmt.Errorf("registro que no es NBSTAT")
+	}
+	off += 10 // tipo, clase, TTL, largo
+	if off >= len(msg) {
+		return nbstatInfo{}, fmt.Errorf("respuesta truncada")
+	}
+	count := int(msg[off])
+	off++
+
+	var info nbstatInfo
+	for i := 0; i < count; i++ {
+		if off+18 > len(msg) {
+			return nbstatInfo{}, fmt.Errorf("tabla de nombres truncada")
+		}
+		name := strings.TrimRight(string(msg[off:off+15]), " \x00")
+		suffix := msg[off+15]
+		group := binary.BigEndian.Uint16(msg[off+16:])&nbnsGroupFlag != 0
+		off += 18
+		if suffix != 0x00 || name == "" {
+			continue
+		}
+		if !group && info.Name == "" {
+			info.Name = name
+		} else if group && info.Workgroup == "" {
+			info.Workgroup = name
+		}
+	}
+	if off+6 <= len(msg) {
+		mac := net.HardwareAddr(msg[off : off+6])
+		if mac.String() != "00:00:00:00:00:00" {
+			info.MAC = mac.String()
+		}
+	}
+	if info.Name == "" {
+		return nbstatInfo{}, fmt.Errorf("sin nombre de equipo")
+	}
+	return info, nil
+}
@@ -0,0 +1,58 @@
+package scan
+
+import (
+	"strings"
+	"testing"
+)
+
+// respuesta NBSTAT de un Windows (RFC 1002 §4.2.18), armada por partes para
+// poder truncarla o cambiar campos
+var (
+	nbstatHeader = "1234" + "8400" + "0000" + "0001" + "0000" + "0000" // id, respuesta autoritativa, 1 registro
+	nbstatName   = "20" + "434b" + strings.Repeat("41", 30) + "00"     // "*" codificado
+	nbstatRR     = "0021" + "0001" + "00000000" + "0065"               // NBSTAT, IN, TTL 0, largo 101
+	nbstatNames  = "03" +
+		"4445534b544f502d30312020202020" + "00" + "0400" + // DESKTOP-01<00> único
+		"574f524b47524f5550202020202020" + "00" + "8400" + // WORKGROUP<00> grupo
+		"4445534b544f502d30312020202020" + "20" + "0400" // DESKTOP-01<20> servidor
+	nbstatStats = "001a2b3c4d5e" + strings.Repeat("00", 40) // unit ID (MAC) y estadísticas
+)
+
+func TestParseNBSTAT(t *testing.T) {
+	full := nbstatHeader + nbstatName + nbstatRR + nbstatNames + nbstatStats
+	cases := []struct {
+		name string
+		msg  string
+		want nbstatInfo
+		err  bool
+	}{
+		{"respuesta completa", full, nbstatInfo{Name: "DESKTOP-01", Workgroup: "WORKGROUP", MAC: "00:1a:2b:3c:4d:5e"}, false},
+		{"nombre comprimido", nbstatHeader + "c00c" + nbstatRR + nbstatNames + nbstatStats,
+			nbstatInfo{Name: "DESKTOP-01", Workgroup: "WORKGROUP", MAC: "00:1a:2b:3c:4d:5e"}, false},
+		{"Samba sin MAC", nbstatHeader + nbstatName + nbstatRR + nbstatNames + strings.Repeat("00", 46),
+			nbstatInfo{Name: "DESKTOP-01", Workgroup: "WORKGROUP"}, false},
+		{"sin estadísticas", nbstatHeader + nbstatName + nbstatRR + nbstatNames,
+			nbstatInfo{Name: "DESKTOP-01", Workgroup: "WORKGROUP"}, false},
+		{"MAC cortada", nbstatHeader + nbstatName + nbstatRR + nbstatNames + "001a2b",
+			nbstatInfo{Name: "DESKTOP-01", Workgroup: "WORKGROUP"}, false},
+		{"otro id", "4321" + full[4:], nbstatInfo{}, true},
+		{"consulta en vez de respuesta", "12340000" + full[8:], nbstatInfo{}, true},
+		{"sin registros", "1234840000000000" + full[16:], nbstatInfo{}, true},
+		{"cabecera truncada", nbstatHeader[:16], nbstatInfo{}, true},
+		{"vacía", "", nbstatInfo{}, true},
+		{"truncada en el nombre", nbstatHeader + nbstatName[:20], nbstatInfo{}, true},
+		{"truncada en el registro", nbstatHeader + nbstatName + nbstatRR[:8], nbstatInfo{}, true},
+		{"sin cantidad de nombres", nbstatHeader + nbstatName + nbstatRR, nbstatInfo{}, true},
+		{"registro NB", nbstatHeader + nbstatName + "0020" + nbstatRR[4:] + nbstatNames, nbstatInfo{}, true},
+		{"tabla de nombres truncada", nbstatHeader + nbstatName + nbstatRR + nbstatNames[:2+36*2+10], nbstatInfo{}, true},
+		{"cantidad mayor que la tabla", nbstatHeader + nbstatName + nbstatRR + "05" + nbstatNames[2:], nbstatInfo{}, true},
+		{"solo nombres de grupo", nbstatHeader + nbstatName + nbstatRR + "01" +
+			"574f524b47524f5550202020202020" + "00" + "8400", nbstatInfo{}, true},
+	}
+	for _, c := range cases {
+		got, err := parseNBSTAT(mustHex(t, c.msg), 0x1234)
+		if (err != nil) != c.err || got != c.want {
+			t.Errorf("%s: parseNBSTAT = %+v, %v; quiero %+v (error %v)", c.name, got, err, c.want, c.err)
+		}
+	}
+}
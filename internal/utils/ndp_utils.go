@@ -0,0 +1,78 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DiscoverIPv6Neighbors descubre hosts IPv6 del segmento local:
+// 1) envía un ping a ff02::1 (all-nodes) por cada interfaz con link-local
+// 2) lee la caché NDP del kernel (Linux)
+// Devuelve las IPs con zona (fe80::1%eth0) listas para escanear, sin las propias.
+func DiscoverIPv6Neighbors(ctx context.Context, timeout time.Duration) []string {
+	own := map[netip.Addr]bool{}
+	found := map[string]bool{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	// primero todas las direcciones propias: las goroutines leen own sin lock
+	var linkLocal []string
+	ifaces, _ := net.Interfaces()
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		addrs, _ := iface.Addrs()
+		hasLinkLocal := false
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if addr, ok := netip.AddrFromSlice(ipnet.IP); ok {
+				own[addr.Unmap()] = true
+				if addr.Is6() && addr.IsLinkLocalUnicast() {
+					hasLinkLocal = true
+				}
+			}
+		}
+		if hasLinkLocal {
+			linkLocal = append(linkLocal, iface.Name)
+		}
+	}
+
+	for _, name := range linkLocal {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			allNodes := netip.MustParseAddr("ff02::1").WithZone(name)
+			// timeout vence siempre: seguimos leyendo respuestas hasta entonces
+			_ = icmpExchange(ctx, allNodes, timeout, func(peer netip.Addr, _ pingReply) bool {
+				mu.Lock()
+				defer mu.Unlock()
+				if !own[peer.WithZone("")] {
+					found[peer.String()] = true
+				}
+				return false
+			})
+		}(name)
+	}
+	wg.Wait()
+
+	for _, ip := range ndpCacheAddrs() {
+		if addr, err := netip.ParseAddr(ip); err == nil && !own[addr.WithZone("")] {
+			found[ip] = true
+		}
+	}
+
+	out := make([]string, 0, len(found))
+	for ip := range found {
+		out = append(out, ip)
+	}
+	sort.Slice(out, func(i, j int) bool { return ipLess(out[i], out[j]) })
+	return out
+}
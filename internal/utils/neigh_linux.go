@@ -0,0 +1,252 @@
+package scan
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// neighEntry una entrada de la tabla de vecinos del kernel (ARP / NDP)
+type neighEntry struct {
+	IP      net.IP
+	MAC     net.HardwareAddr
+	Ifindex int
+	State   uint16
+}
+
+// neighborTable lee la tabla de vecinos del kernel vía rtnetlink (RTM_GETNEIGH),
+// sin lanzar `ip neigh` ni parsear texto. family es unix.AF_INET o unix.AF_INET6.
+func neighborTable(family int) ([]neighEntry, error) {
+	b, err := syscall.NetlinkRIB(unix.RTM_GETNEIGH, family)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := syscall.ParseNetlinkMessage(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []neighEntry
+	for _, m := range msgs {
+		if m.Header.Type == unix.NLMSG_DONE {
+			break
+		}
+		if m.Header.Type != unix.RTM_NEWNEIGH || len(m.Data) < unix.SizeofNdMsg {
+			continue
+		}
+		// struct ndmsg: family(1) pad(3) ifindex(4) state(2) flags(1) type(1)
+		e := neighEntry{
+			Ifindex: int(int32(binary.NativeEndian.Uint32(m.Data[4:8]))),
+			State:   binary.NativeEndian.Uint16(m.Data[8:10]),
+		}
+		attrs := m.Data[unix.SizeofNdMsg:]
+		for len(attrs) >= unix.SizeofRtAttr {
+			l := int(binary.NativeEndian.Uint16(attrs[0:2]))
+			typ := binary.NativeEndian.Uint16(attrs[2:4])
+			if l < unix.SizeofRtAttr || l > len(attrs) {
+				break
+			}
+			val := attrs[unix.SizeofRtAttr:l]
+			switch typ {
+			case unix.NDA_DST:
+				e.IP = net.IP(append([]byte(nil), val...))
+			case unix.NDA_LLADDR:
+				e.MAC = net.HardwareAddr(append([]byte(nil), val...))
+			}
+			// los atributos van alineados a 4 bytes
+			next := (l + unix.RTA_ALIGNTO - 1) &^ (unix.RTA_ALIGNTO - 1)
+			if next > len(attrs) {
+				break
+			}
+			attrs = attrs[next:]
+		}
+		if e.IP != nil {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// usable indica si la entrada tiene una MAC válida y resuelta
+func (e neighEntry) usable() bool {
+	if e.State&(unix.NUD_INCOMPLETE|unix.NUD_FAILED|unix.NUD_NOARP) != 0 {
+		return false
+	}
+	if len(e.MAC) != 6 {
+		return false
+	}
+	for _, b := range e.MAC {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// macFromNeighborTable busca la MAC de ip en la tabla de vecinos del kernel.
+// Solo devuelve la MAC de una entrada cuya dirección coincide exactamente con ip.
+func macFromNeighborTable(ip string) string {
+	target := net.ParseIP(stripZone(ip))
+	if target == nil {
+		return ""
+	}
+	family := unix.AF_INET6
+	if target.To4() != nil {
+		family = unix.AF_INET
+	}
+	// link-local con zona: la entrada debe ser de esa interfaz
+	ifindex := 0
+	if i := strings.IndexByte(ip, '%'); i >= 0 {
+		if iface, err := net.InterfaceByName(ip[i+1:]); err == nil {
+			ifindex = iface.Index
+		}
+	}
+	entries, err := neighborTable(family)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		if ifindex != 0 && e.Ifindex != ifindex {
+			continue
+		}
+		if e.IP.Equal(target) && e.usable() {
+			return e.MAC.String()
+		}
+	}
+	return ""
+}
+
+// ndpCacheAddrs devuelve las IPv6 unicast presentes en la caché NDP del kernel.
+// Las link-local se devuelven con zona (fe80::1%eth0).
+func ndpCacheAddrs() []string {
+	entries, err := neighborTable(unix.AF_INET6)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, e := range entries {
+		if !e.usable() || e.IP.IsMulticast() || e.IP.IsUnspecified() {
+			continue
+		}
+		ip := e.IP.String()
+		if e.IP.IsLinkLocalUnicast() {
+			iface, err := net.InterfaceByIndex(e.Ifindex)
+			if err != nil {
+				continue
+			}
+			ip += "%" + iface.Name
+		}
+		out = append(out, ip)
+	}
+	return out
+}
+
+// macFromARPRequest envía un ARP request propio por la interfaz cuyo segmento
+// contiene ip y espera la respuesta de esa IP. Requiere CAP_NET_RAW; sin permisos
+// o si ip no está en un segmento local devuelve "".
+func macFromARPRequest(ctx context.Context, ip string, timeout time.Duration) string {
+	target := net.ParseIP(ip).To4()
+	if target == nil {
+		return ""
+	}
+	iface, src := localInterfaceFor(target)
+	if iface == nil {
+		return ""
+	}
+
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_DGRAM, int(htons(unix.ETH_P_ARP)))
+	if err != nil {
+		return ""
+	}
+	defer unix.Close(fd)
+
+	if err := unix.Bind(fd, &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ARP), Ifindex: iface.Index}); err != nil {
+		return ""
+	}
+
+	// ARP request: hardware ethernet(1), protocolo IPv4, op=1 (request)
+	req := make([]byte, 28)
+	binary.BigEndian.PutUint16(req[0:2], 1)
+	binary.BigEndian.PutUint16(req[2:4], unix.ETH_P_IP)
+	req[4], req[5] = 6, 4
+	binary.BigEndian.PutUint16(req[6:8], 1)
+	copy(req[8:14], iface.HardwareAddr)
+	copy(req[14:18], src)
+	copy(req[24:28], target)
+
+	bcast := &unix.SockaddrLinklayer{
+		Protocol: htons(unix.ETH_P_ARP),
+		Ifindex:  iface.Index,
+		Halen:    6,
+		Addr:     [8]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+	if err := unix.Sendto(fd, req, 0, bcast); err != nil {
+		return ""
+	}
+
+	// leemos en tramos cortos para poder revisar ctx y el deadline
+	tv := unix.NsecToTimeval((100 * time.Millisecond).Nanoseconds())
+	_ = unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv)
+
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 128)
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+				continue
+			}
+			return ""
+		}
+		if n < 28 || binary.BigEndian.Uint16(buf[6:8]) != 2 {
+			continue
+		}
+		// solo aceptamos la respuesta cuyo sender IP es la IP consultada
+		if !net.IP(buf[14:18]).Equal(target) {
+			continue
+		}
+		return net.HardwareAddr(append([]byte(nil), buf[8:14]...)).String()
+	}
+	return ""
+}
+
+// localInterfaceFor devuelve la interfaz (y su IPv4) cuyo segmento contiene ip
+func localInterfaceFor(ip net.IP) (*net.Interface, net.IP) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, nil
+	}
+	for i := range ifaces {
+		iface := &ifaces[i]
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) != 6 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok || ipnet.IP.To4() == nil {
+				continue
+			}
+			if ipnet.Contains(ip) {
+				return iface, ipnet.IP.To4()
+			}
+		}
+	}
+	return nil, nil
+}
+
+// htons convierte a orden de red para los campos de protocolo de AF_PACKET
+func htons(v uint16) uint16 {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	return binary.NativeEndian.Uint16(b[:])
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package scan
+
+import (
+	"context"
+	"time"
+)
+
+// macFromNeighborTable sin rtnetlink fuera de Linux; getMAC usa el comando arp.
+func macFromNeighborTable(ip string) string {
+	return ""
+}
+
+// macFromARPRequest el ARP activo solo está implementado en Linux (AF_PACKET).
+func macFromARPRequest(ctx context.Context, ip string, timeout time.Duration) string {
+	return ""
+}
+
+// ndpCacheAddrs la caché NDP solo se lee en Linux (rtnetlink).
+func ndpCacheAddrs() []string {
+	return nil
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package scan
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isConnRefused el puerto respondió con RST
+func isConnRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// isResourceErr el sistema se quedó sin descriptores, buffers o puertos
+// locales: el puerto no se llegó a probar
+func isResourceErr(err error) bool {
+	return errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) ||
+		errors.Is(err, syscall.ENOBUFS) || errors.Is(err, syscall.EADDRNOTAVAIL)
+}
+
+// openFileLimit límite blando de archivos abiertos del proceso (0 = desconocido)
+func openFileLimit() int {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil || rl.Cur > 1<<30 {
+		return 0
+	}
+	return int(rl.Cur)
+}
@@ -0,0 +1,24 @@
+package scan
+
+import (
+	"errors"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// isConnRefused el puerto respondió con RST. En Windows connectex devuelve
+// WSAECONNREFUSED, que no se traduce a syscall.ECONNREFUSED.
+func isConnRefused(err error) bool {
+	return errors.Is(err, windows.WSAECONNREFUSED) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// isResourceErr Winsock se quedó sin sockets, buffers o puertos locales: el
+// puerto no se llegó a probar
+func isResourceErr(err error) bool {
+	return errors.Is(err, windows.WSAEMFILE) || errors.Is(err, windows.WSAENOBUFS) ||
+		errors.Is(err, windows.WSAEADDRINUSE)
+}
+
+// openFileLimit Windows no limita los sockets por proceso como RLIMIT_NOFILE
+func openFileLimit() int { return 0 }
@@ -0,0 +1,233 @@
+package scan
+
+import (
+	"context"
+	"encoding/binary"
+	"escaner/internal/models"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ----------------------- huella pasiva del sistema operativo -------------------------
+
+// familias que distingue guessOS
+const (
+	osWindows   = "Windows"
+	osLinux     = "Linux"
+	osBSD       = "BSD/macOS"
+	osEmbedded  = "Embedded RTOS"
+	osNetworkOS = "Network OS"
+)
+
+// tcpStack lo que revela el SYN-ACK de un puerto abierto. Con el paquete crudo
+// (Source "syn-ack") se conoce el orden exacto de las opciones; con TCP_INFO solo
+// cuáles negoció el kernel.
+type tcpStack struct {
+	Source  string // "syn-ack" o "tcp_info"
+	TTL     int    // TTL del SYN-ACK (0 si no se capturó)
+	Window  int
+	MSS     int
+	WScale  int    // -1 = sin window scale
+	Options string // orden de las opciones: "M,S,T,N,W"
+	SACK    bool
+	TS      bool
+}
+
+// firmas de SYN-ACK conocidas (orden de opciones, como p0f): M=MSS N=NOP
+// W=window scale S=SACK permitido T=timestamps E=fin de opciones. Un mismo orden
+// puede sumar a varias familias.
+var synAckLayouts = []struct {
+	options string
+	family  string
+	score   float64
+}{
+	{"M,S,T,N,W", osLinux, 0.45},
+	{"M,N,N,S,N,W", osLinux, 0.35}, // sin timestamps
+	{"M,N,N,T,N,W", osLinux, 0.3},  // sin SACK
+	{"M,N,W,N,N,T,S,E", osBSD, 0.45},
+	{"M,N,W,N,N,T,S", osBSD, 0.4}, // macOS/iOS
+	{"M,N,W,S,T", osBSD, 0.4},     // FreeBSD
+	{"M,N,W,N,N,T", osBSD, 0.3},
+	{"M,N,W,N,N,S", osWindows, 0.45},
+	{"M,N,W,S", osWindows, 0.35},
+	{"M,N,W,N,N,T,N,N,S", osWindows, 0.35}, // con timestamps habilitados
+	{"M", osEmbedded, 0.2},                 // lwIP, uIP, VxWorks
+	{"M", osNetworkOS, 0.1},                // Cisco IOS
+	{"M,N,N,S", osEmbedded, 0.2},
+	{"", osEmbedded, 0.3},
+}
+
+// ventanas típicas del SYN-ACK
+var synAckWindows = map[int]string{
+	65160: osLinux, 29200: osLinux, 28960: osLinux, 14480: osLinux, 5792: osLinux, 43440: osLinux,
+	8192: osWindows, 64240: osWindows,
+	4128: osNetworkOS, // Cisco IOS
+	2144: osEmbedded, 5840: osEmbedded, 1460: osEmbedded, 2920: osEmbedded,
+}
+
+// guessOS combina el TTL del ICMP echo y la huella del SYN-ACK (st puede ser
+// nil) en una familia de sistema con una confianza entre 0 y 1
+func guessOS(ttl int, st *tcpStack) *models.OSGuess {
+	if ttl == 0 && st != nil {
+		ttl = st.TTL
+	}
+	if ttl == 0 && st == nil {
+		return nil
+	}
+	score := map[string]float64{}
+	guess := &models.OSGuess{TTL: ttl, Source: "ttl"}
+	// sin SYN-ACK (ningún puerto abierto, o un agente fuera de Linux: ver
+	// osfp_other.go) la huella es solo este TTL y no pasa de 0.5 para Windows ni
+	// de 0.4 para Network OS; las reglas os-windows y os-network de
+	// device_rules.json piden justo eso. En Windows el TTL llega del comando ping
+	// (tryPing): si tampoco hay, no hay huella y esas reglas no aplican
+	if ttl > 0 {
+		guess.InitialTTL = initialTTL(ttl)
+		switch guess.InitialTTL {
+		case 128:
+			score[osWindows] += 0.5
+		case 255:
+			score[osNetworkOS] += 0.4
+			score[osEmbedded] += 0.15
+		case 64:
+			score[osLinux] += 0.25
+			score[osBSD] += 0.2
+			score[osEmbedded] += 0.1
+		case 32:
+			score[osEmbedded] += 0.3
+		}
+	}
+	if st != nil {
+		guess.Source = st.Source
+		guess.Window = st.Window
+		guess.MSS = st.MSS
+		guess.WScale = max(st.WScale, 0)
+		guess.Options = st.Options
+		if st.Source == "syn-ack" {
+			for _, l := range synAckLayouts {
+				if l.options == st.Options {
+					score[l.family] += l.score
+				}
+			}
+			if f, ok := synAckWindows[st.Window]; ok {
+				score[f] += 0.1
+			}
+		} else {
+			// TCP_INFO: solo qué opciones se negociaron
+			switch {
+			case st.TS && st.SACK && st.WScale >= 0:
+				score[osLinux] += 0.2
+				score[osBSD] += 0.15
+			case st.SACK && st.WScale >= 0:
+				score[osWindows] += 0.25
+			case !st.TS && !st.SACK && st.WScale < 0:
+				score[osEmbedded] += 0.2
+				score[osNetworkOS] += 0.1
+			}
+		}
+		switch st.WScale {
+		case 8:
+			score[osWindows] += 0.05
+		case 7:
+			score[osLinux] += 0.05
+		case 6, 5:
+			score[osBSD] += 0.05
+		}
+	}
+
+	var families []string
+	for f := range score {
+		families = append(families, f)
+	}
+	if len(families) == 0 {
+		return nil
+	}
+	// mayor puntaje; a igual puntaje, orden alfabético para que sea estable
+	slices.SortFunc(families, func(a, b string) int {
+		if score[a] != score[b] {
+			if score[a] > score[b] {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+	best := families[0]
+	conf := min(score[best], 0.95)
+	if len(families) > 1 && score[best]-score[families[1]] < 0.1 {
+		// dos familias casi empatadas: la elección es poco fiable
+		conf *= 0.7
+	}
+	guess.Family = best
+	guess.Confidence = math.Round(conf*100) / 100
+	return guess
+}
+
+// initialTTL TTL con el que salió el paquete: el valor estándar inmediato superior
+// (32, 64, 128 o 255) al observado, asumiendo menos de 32 saltos
+func initialTTL(ttl int) int {
+	for _, v := range []int{32, 64, 128} {
+		if ttl <= v {
+			return v
+		}
+	}
+	return 255
+}
+
+// parseTCPOptions recorre las opciones de un segmento TCP y devuelve su orden,
+// MSS y window scale (-1 si no viene)
+func parseTCPOptions(opts []byte) (layout string, mss, wscale int) {
+	var kinds []string
+	wscale = -1
+	for len(opts) > 0 {
+		kind := opts[0]
+		if kind == 0 {
+			kinds = append(kinds, "E")
+			break
+		}
+		if kind == 1 {
+			kinds = append(kinds, "N")
+			opts = opts[1:]
+			continue
+		}
+		if len(opts) < 2 || int(opts[1]) < 2 || int(opts[1]) > len(opts) {
+			break
+		}
+		val := opts[2:opts[1]]
+		switch kind {
+		case 2:
+			kinds = append(kinds, "M")
+			if len(val) == 2 {
+				mss = int(binary.BigEndian.Uint16(val))
+			}
+		case 3:
+			kinds = append(kinds, "W")
+			if len(val) == 1 {
+				wscale = int(val[0])
+			}
+		case 4:
+			kinds = append(kinds, "S")
+		case 8:
+			kinds = append(kinds, "T")
+		default:
+			kinds = append(kinds, "?"+strconv.Itoa(int(kind)))
+		}
+		opts = opts[opts[1]:]
+	}
+	return strings.Join(kinds, ","), mss, wscale
+}
+
+// fingerprintOS huella del sistema del host: TTL del ping más el SYN-ACK de un
+// puerto TCP abierto (port 0 = solo TTL)
+func fingerprintOS(ctx context.Context, ip string, port int, ttl int, timeout time.Duration, throttle *scanThrottle) *models.OSGuess {
+	var st *tcpStack
+	if port > 0 && throttle.wait(ctx, ip) == nil {
+		if s, ok := tcpStackProbe(ctx, ip, port, timeout); ok {
+			st = &s
+		}
+	}
+	return guessOS(ttl, st)
+}
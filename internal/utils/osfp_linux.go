@@ -0,0 +1,145 @@
+package scan
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/bpf"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/sys/unix"
+)
+
+// bits de tcpi_options (linux/tcp.h); x/sys no los define
+const (
+	tcpiOptTimestamps = 1
+	tcpiOptSACK       = 2
+	tcpiOptWScale     = 4
+)
+
+// tcpStackProbe conecta al puerto y captura el SYN-ACK con un socket crudo
+// (IPv4, requiere CAP_NET_RAW): TTL, ventana y opciones tal como vinieron. Sin
+// permisos o en IPv6 lee de TCP_INFO las opciones que negoció el kernel.
+func tcpStackProbe(ctx context.Context, ip string, port int, timeout time.Duration) (tcpStack, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return tcpStack{}, false
+	}
+	var raw *ipv4.RawConn
+	if addr.Unmap().Is4() {
+		raw = listenSynAck(addr.Unmap(), port)
+	}
+	if raw != nil {
+		defer raw.Close()
+	}
+
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil {
+		return tcpStack{}, false
+	}
+	defer conn.Close()
+	local := conn.LocalAddr().(*net.TCPAddr).Port
+
+	if raw != nil {
+		if st, ok := readSynAck(ctx, raw, local, timeout); ok {
+			return st, true
+		}
+	}
+	return tcpInfoStack(conn.(*net.TCPConn))
+}
+
+// listenSynAck socket crudo TCP con un filtro BPF que solo deja pasar los SYN-ACK
+// de src:port; nil si no hay permisos
+func listenSynAck(src netip.Addr, port int) *ipv4.RawConn {
+	c, err := net.ListenIP("ip4:tcp", nil)
+	if err != nil {
+		return nil
+	}
+	raw, err := ipv4.NewRawConn(c)
+	if err != nil {
+		c.Close()
+		return nil
+	}
+	// el paquete llega con el encabezado IP: X = largo del encabezado
+	prog, err := bpf.Assemble([]bpf.Instruction{
+		bpf.LoadAbsolute{Off: 12, Size: 4},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: binary.BigEndian.Uint32(src.AsSlice()), SkipFalse: 7},
+		bpf.LoadMemShift{Off: 0},
+		bpf.LoadIndirect{Off: 0, Size: 2},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: uint32(port), SkipFalse: 4},
+		bpf.LoadIndirect{Off: 13, Size: 1},
+		bpf.ALUOpConstant{Op: bpf.ALUOpAnd, Val: 0x12},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: 0x12, SkipFalse: 1},
+		bpf.RetConstant{Val: 0xffff},
+		bpf.RetConstant{Val: 0},
+	})
+	if err == nil {
+		// sin filtro igual funciona: readSynAck descarta lo que no corresponde
+		_ = raw.SetBPF(prog)
+	}
+	return raw
+}
+
+// readSynAck busca en lo capturado el SYN-ACK dirigido al puerto local de la
+// conexión (el handshake ya terminó, así que está en el buffer)
+func readSynAck(ctx context.Context, raw *ipv4.RawConn, local int, timeout time.Duration) (tcpStack, bool) {
+	stop := context.AfterFunc(ctx, func() { raw.Close() })
+	defer stop()
+	_ = raw.SetReadDeadline(time.Now().Add(min(timeout, 200*time.Millisecond)))
+	buf := make([]byte, 1500)
+	for {
+		h, p, _, err := raw.ReadFrom(buf)
+		if err != nil {
+			return tcpStack{}, false
+		}
+		if len(p) < 20 || int(binary.BigEndian.Uint16(p[2:4])) != local || p[13]&0x12 != 0x12 {
+			continue
+		}
+		off := int(p[12]>>4) * 4
+		if off < 20 || off > len(p) {
+			continue
+		}
+		st := tcpStack{
+			Source: "syn-ack",
+			TTL:    h.TTL,
+			Window: int(binary.BigEndian.Uint16(p[14:16])),
+		}
+		st.Options, st.MSS, st.WScale = parseTCPOptions(p[20:off])
+		return st, true
+	}
+}
+
+// tcpInfoStack opciones negociadas según el kernel (sin orden ni ventana original)
+func tcpInfoStack(conn *net.TCPConn) (tcpStack, bool) {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return tcpStack{}, false
+	}
+	var info *unix.TCPInfo
+	var serr error
+	if err := rc.Control(func(fd uintptr) {
+		info, serr = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+	}); err != nil || serr != nil {
+		return tcpStack{}, false
+	}
+	st := tcpStack{
+		Source: "tcp_info",
+		MSS:    int(info.Snd_mss),
+		WScale: -1,
+		SACK:   info.Options&tcpiOptSACK != 0,
+		TS:     info.Options&tcpiOptTimestamps != 0,
+	}
+	if info.Options&tcpiOptWScale != 0 {
+		// el valor está en un bitfield que x/sys no expone; basta saber que vino
+		st.WScale = 0
+	}
+	if st.TS {
+		// snd_mss descuenta los 12 bytes de la opción timestamp
+		st.MSS += 12
+	}
+	return st, true
+}
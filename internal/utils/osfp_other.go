@@ -0,0 +1,17 @@
+//go:build !linux
+
+package scan
+
+import (
+	"context"
+	"time"
+)
+
+// tcpStackProbe capturar el SYN-ACK solo está implementado en Linux (socket crudo
+// TCP / TCP_INFO). En Windows los sockets crudos no reciben TCP y las
+// estadísticas por conexión (GetPerTcpConnectionEStats) solo dan el MSS, que no
+// distingue sistemas: la huella usa solo el TTL y su confianza queda en los
+// valores de TTL de guessOS.
+func tcpStackProbe(ctx context.Context, ip string, port int, timeout time.Duration) (tcpStack, bool) {
+	return tcpStack{}, false
+}
@@ -0,0 +1,74 @@
+package scan
+
+import "testing"
+
+func TestParseTCPOptions(t *testing.T) {
+	cases := []struct {
+		name   string
+		opts   string // hex de las opciones del SYN-ACK
+		layout string
+		mss    int
+		wscale int
+	}{
+		{"Linux", "020405b4" + "0402" + "080a" + "a1b2c3d4" + "00000000" + "01" + "030307", "M,S,T,N,W", 1460, 7},
+		{"Windows 10", "020405b4" + "01" + "030308" + "0101" + "0402", "M,N,W,N,N,S", 1460, 8},
+		{"macOS", "020405b4" + "01" + "030306" + "0101" + "080a" + "5e1c0a33" + "00000000" + "0402" + "0000", "M,N,W,N,N,T,S,E", 1460, 6},
+		{"FreeBSD", "020405b4" + "01" + "030306" + "0402" + "080a" + "12345678" + "00000000", "M,N,W,S,T", 1460, 6},
+		{"lwIP", "020405b4", "M", 1460, -1},
+		{"MSS de PPPoE", "020405ac" + "0101" + "0402", "M,N,N,S", 1452, -1},
+		{"opción desconocida", "020405b4" + "1e04abcd", "M,?30", 1460, -1},
+		{"sin opciones", "", "", 0, -1},
+		{"MSS truncado", "020405", "", 0, -1},
+		{"largo cero", "0200" + "020405b4", "", 0, -1},
+		{"largo uno", "0401", "", 0, -1},
+		{"MSS con largo raro", "0203ff", "M", 0, -1},
+		{"window scale truncado", "0101" + "0303", "N,N", 0, -1},
+		{"fin de opciones y relleno", "020405b4" + "00" + "030307", "M,E", 1460, -1},
+	}
+	for _, c := range cases {
+		layout, mss, wscale := parseTCPOptions(mustHex(t, c.opts))
+		if layout != c.layout || mss != c.mss || wscale != c.wscale {
+			t.Errorf("%s: parseTCPOptions = %q %d %d, quiero %q %d %d", c.name, layout, mss, wscale, c.layout, c.mss, c.wscale)
+		}
+	}
+}
+
+func TestGuessOS(t *testing.T) {
+	linux := &tcpStack{Source: "syn-ack", Window: 65160, MSS: 1460, WScale: 7, Options: "M,S,T,N,W"}
+	windows := &tcpStack{Source: "syn-ack", TTL: 128, Window: 64240, MSS: 1460, WScale: 8, Options: "M,N,W,N,N,S"}
+	macOS := &tcpStack{Source: "syn-ack", Window: 65535, MSS: 1460, WScale: 6, Options: "M,N,W,N,N,T,S,E"}
+	rtos := &tcpStack{Source: "syn-ack", Window: 1460, WScale: -1}
+	cases := []struct {
+		name   string
+		ttl    int
+		st     *tcpStack
+		family string // "" = sin huella
+		conf   float64
+		source string
+	}{
+		{"sin datos", 0, nil, "", 0, ""},
+		{"TTL de Windows", 128, nil, osWindows, 0.5, "ttl"},
+		{"TTL de Windows tras saltos", 116, nil, osWindows, 0.5, "ttl"},
+		{"TTL de equipo de red", 255, nil, osNetworkOS, 0.4, "ttl"},
+		{"TTL 64 casi empatado", 64, nil, osLinux, 0.18, "ttl"},
+		{"TTL 32", 30, nil, osEmbedded, 0.3, "ttl"},
+		{"SYN-ACK de Linux", 64, linux, osLinux, 0.85, "syn-ack"},
+		{"SYN-ACK de Windows con su TTL", 0, windows, osWindows, 0.95, "syn-ack"},
+		{"SYN-ACK de macOS", 64, macOS, osBSD, 0.7, "syn-ack"},
+		{"SYN-ACK sin opciones", 0, rtos, osEmbedded, 0.4, "syn-ack"},
+		{"TCP_INFO de Linux", 64, &tcpStack{Source: "tcp_info", TS: true, SACK: true, WScale: 7}, osLinux, 0.5, "tcp_info"},
+		{"TCP_INFO sin nada reconocible", 0, &tcpStack{Source: "tcp_info", SACK: true, WScale: -1}, "", 0, ""},
+	}
+	for _, c := range cases {
+		g := guessOS(c.ttl, c.st)
+		if c.family == "" {
+			if g != nil {
+				t.Errorf("%s: guessOS = %+v, quiero nil", c.name, g)
+			}
+			continue
+		}
+		if g == nil || g.Family != c.family || g.Confidence != c.conf || g.Source != c.source {
+			t.Errorf("%s: guessOS = %+v, quiero %s %.2f (%s)", c.name, g, c.family, c.conf, c.source)
+		}
+	}
+}
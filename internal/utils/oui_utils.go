@@ -0,0 +1,180 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ----------------------- fabricantes por OUI (registro IEEE) -------------------------
+
+// registro por defecto, embebido en el binario. "go generate" lo regenera
+// descargando oui.csv, mam.csv y oas.csv del IEEE (ver cmd/ouigen).
+//
+//go:generate go run ../../cmd/ouigen -o data/oui.tsv
+//go:embed data/oui.tsv
+var defaultOUIData []byte
+
+// ieeeRegistrationAuthority titular de los bloques MA-L que el IEEE subdivide en
+// MA-M / MA-S: no identifica al fabricante
+const ieeeRegistrationAuthority = "IEEE Registration Authority"
+
+// largos de prefijo en dígitos hex, del más específico al menos: MA-S (/36), MA-M (/28), MA-L (/24)
+var ouiPrefixLens = []int{9, 7, 6}
+
+var (
+	ouiMu sync.RWMutex
+	ouiDB map[string]string // prefijo hex en mayúsculas -> organización
+)
+
+func init() {
+	db, err := ParseOUIRegistry(bytes.NewReader(defaultOUIData))
+	if err != nil {
+		panic("oui.tsv embebido inválido: " + err.Error())
+	}
+	ouiDB = db
+}
+
+// LookupVendor devuelve el fabricante de la MAC buscando el prefijo más largo
+// registrado (MA-S, luego MA-M, luego MA-L), o "" si no está en el registro.
+func LookupVendor(mac string) string {
+	hex := strings.ToUpper(strings.ReplaceAll(normalizeMAC(strings.TrimSpace(mac)), ":", ""))
+	if len(hex) != 12 || !isHex(hex) {
+		return ""
+	}
+	ouiMu.RLock()
+	defer ouiMu.RUnlock()
+	for _, n := range ouiPrefixLens {
+		if v, ok := ouiDB[hex[:n]]; ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// LoadOUIDatabase agrega al registro en memoria las entradas de un archivo local
+// (CSV o TXT del IEEE, o el TSV embebido). Las entradas existentes se reemplazan.
+func LoadOUIDatabase(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error abriendo registro OUI: %w", err)
+	}
+	defer f.Close()
+	db, err := ParseOUIRegistry(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	ouiMu.Lock()
+	defer ouiMu.Unlock()
+	for k, v := range db {
+		ouiDB[k] = v
+	}
+	return nil
+}
+
+// ParseOUIRegistry lee un registro de fabricantes en cualquiera de estos formatos:
+//   - CSV del IEEE (oui.csv, mam.csv, oas.csv): Registry,Assignment,Organization Name,...
+//   - TXT del IEEE (oui.txt): líneas "00-1B-63   (hex)		Apple, Inc."
+//   - TSV propio (data/oui.tsv): "001B63<TAB>Apple, Inc.", con comentarios '#'
+func ParseOUIRegistry(r io.Reader) (map[string]string, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(len("Registry,"))
+	if strings.EqualFold(string(head), "Registry,") {
+		return parseOUICSV(br)
+	}
+
+	db := map[string]string{}
+	ieeeTXT := false
+	sc := bufio.NewScanner(br)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// TXT del IEEE: solo interesan las líneas "(hex)"; "(base 16)" y las
+		// direcciones postales se ignoran
+		if prefix, org, ok := strings.Cut(line, "(hex)"); ok {
+			ieeeTXT = true
+			addOUI(db, strings.ReplaceAll(strings.TrimSpace(prefix), "-", ""), org)
+			continue
+		}
+		if ieeeTXT {
+			continue
+		}
+		prefix, org, ok := strings.Cut(line, "\t")
+		if !ok || !addOUI(db, prefix, org) {
+			return nil, fmt.Errorf("línea %d: entrada OUI inválida %q", n, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func parseOUICSV(r io.Reader) (map[string]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("csv OUI inválido: %w", err)
+	}
+	db := map[string]string{}
+	for i, rec := range records {
+		if i == 0 || len(rec) < 3 {
+			continue // encabezado
+		}
+		if !addOUI(db, rec[1], rec[2]) {
+			return nil, fmt.Errorf("fila %d: prefijo OUI inválido %q", i+1, rec[1])
+		}
+	}
+	return db, nil
+}
+
+// addOUI valida el prefijo (6, 7 o 9 dígitos hex) y lo agrega al mapa. Los
+// bloques del propio IEEE se descartan: sin la entrada MA-M / MA-S que
+// corresponde es mejor no responder que responder "IEEE Registration Authority".
+func addOUI(db map[string]string, prefix, org string) bool {
+	prefix = strings.ToUpper(strings.TrimSpace(prefix))
+	if !slices.Contains(ouiPrefixLens, len(prefix)) || !isHex(prefix) {
+		return false
+	}
+	if org = strings.TrimSpace(org); org != "" && !strings.EqualFold(org, ieeeRegistrationAuthority) {
+		db[prefix] = org
+	}
+	return true
+}
+
+// WriteOUIRegistry escribe el registro en el formato TSV embebido, ordenado por prefijo
+func WriteOUIRegistry(w io.Writer, db map[string]string) error {
+	keys := make([]string, 0, len(db))
+	for k := range db {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	bw := bufio.NewWriter(w)
+	for _, k := range keys {
+		// el TSV no admite tabs ni saltos dentro del nombre
+		org := strings.Join(strings.Fields(db[k]), " ")
+		if _, err := fmt.Fprintf(bw, "%s\t%s\n", k, org); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !((c >= '0' && c <= '9') || (c >= 'A' && c <= 'F') || (c >= 'a' && c <= 'f')) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+package scan
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ----------------------- perfiles de escaneo -------------------------
+
+// perfiles por defecto, embebidos en el binario
+//
+//go:embed data/scan_profiles.json
+var defaultScanProfilesJSON []byte
+
+// DefaultProfile perfil que se usa cuando no se elige ninguno
+const DefaultProfile = "standard"
+
+// ScanProfile conjunto con nombre de puertos, tiempos, límites y sondas. Las
+// sondas que no figuran quedan apagadas.
+type ScanProfile struct {
+	Name         string `json:"name"`
+	Description  string `json:"description,omitempty"`
+	Ports        string `json:"ports"` // sintaxis de ParsePortSpec: "tcp:22,80 udp:161"
+	TimeoutMs    int    `json:"timeout_ms"`
+	Concurrency  int    `json:"concurrency"`
+	Rate         int    `json:"rate,omitempty"`
+	SubnetRate   int    `json:"subnet_rate,omitempty"`
+	Retries      int    `json:"retries,omitempty"`
+	Adaptive     bool   `json:"adaptive,omitempty"`       // timeouts según el RTT de cada host
+	MaxTimeoutMs int    `json:"max_timeout_ms,omitempty"` // tope de los timeouts adaptativos
+	AllPorts     bool   `json:"all_ports,omitempty"`
+	Services     bool   `json:"services,omitempty"` // banners y sondas de servicio
+	MDNS         bool   `json:"mdns,omitempty"`
+	NetBIOS      bool   `json:"netbios,omitempty"`
+	SSDP         bool   `json:"ssdp,omitempty"`
+	SNMP         bool   `json:"snmp,omitempty"` // con DefaultSNMPCredentials salvo que se indiquen otras
+	TLS          bool   `json:"tls,omitempty"`
+	HTTP         bool   `json:"http,omitempty"`
+	RTSP         bool   `json:"rtsp,omitempty"`
+	SSH          bool   `json:"ssh,omitempty"`
+	OS           bool   `json:"os,omitempty"`
+}
+
+type scanProfileFile struct {
+	Profiles []ScanProfile `json:"profiles"`
+}
+
+var (
+	scanProfilesMu sync.RWMutex
+	scanProfiles   []ScanProfile
+)
+
+func init() {
+	profiles, err := parseScanProfiles(defaultScanProfilesJSON)
+	if err != nil {
+		panic("scan_profiles.json embebido inválido: " + err.Error())
+	}
+	scanProfiles = profiles
+}
+
+// LoadScanProfiles carga perfiles desde un archivo JSON (ver LoadScanProfilesJSON)
+func LoadScanProfiles(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error leyendo perfiles de escaneo: %w", err)
+	}
+	if err := LoadScanProfilesJSON(data); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadScanProfilesJSON aplica un conjunto de perfiles. Un perfil con el mismo
+// nombre que uno existente lo reemplaza completo; los nuevos se agregan.
+func LoadScanProfilesJSON(data []byte) error {
+	profiles, err := parseScanProfiles(data)
+	if err != nil {
+		return err
+	}
+	scanProfilesMu.Lock()
+	defer scanProfilesMu.Unlock()
+	merged := slices.Clone(scanProfiles)
+	for _, p := range profiles {
+		i := slices.IndexFunc(merged, func(q ScanProfile) bool { return q.Name == p.Name })
+		if i >= 0 {
+			merged[i] = p
+		} else {
+			merged = append(merged, p)
+		}
+	}
+	scanProfiles = merged
+	return nil
+}
+
+func parseScanProfiles(data []byte) ([]ScanProfile, error) {
+	var f scanProfileFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("json de perfiles inválido: %w", err)
+	}
+	for i, p := range f.Profiles {
+		if p.Name == "" {
+			return nil, fmt.Errorf("perfil #%d sin nombre", i+1)
+		}
+		if p.TimeoutMs <= 0 || p.Concurrency <= 0 {
+			return nil, fmt.Errorf("perfil %s: timeout_ms y concurrency deben ser mayores que 0", p.Name)
+		}
+		if p.Retries < 0 {
+			return nil, fmt.Errorf("perfil %s: retries negativo", p.Name)
+		}
+		if p.MaxTimeoutMs < 0 {
+			return nil, fmt.Errorf("perfil %s: max_timeout_ms negativo", p.Name)
+		}
+	}
+	return f.Profiles, nil
+}
+
+// LookupProfile busca un perfil por nombre (sin distinguir mayúsculas)
+func LookupProfile(name string) (ScanProfile, error) {
+	scanProfilesMu.RLock()
+	defer scanProfilesMu.RUnlock()
+	for _, p := range scanProfiles {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+	}
+	names := make([]string, len(scanProfiles))
+	for i, p := range scanProfiles {
+		names[i] = p.Name
+	}
+	return ScanProfile{}, fmt.Errorf("perfil de escaneo desconocido %q (disponibles: %s)", name, strings.Join(names, ", "))
+}
+
+// Options traduce el perfil a ScanOptions
+func (p ScanProfile) Options() ScanOptions {
+	tcp, udp := ParsePortSpec(p.Ports)
+	opts := ScanOptions{
+		Ports:            tcp,
+		UDPPorts:         udp,
+		Timeout:          time.Duration(p.TimeoutMs) * time.Millisecond,
+		Concurrency:      p.Concurrency,
+		Retries:          p.Retries,
+		AdaptiveTimeout:  p.Adaptive,
+		MaxTimeout:       time.Duration(p.MaxTimeoutMs) * time.Millisecond,
+		AllPorts:         p.AllPorts,
+		ServiceDetection: p.Services,
+		Rate:             p.Rate,
+		SubnetRate:       p.SubnetRate,
+		MDNS:             p.MDNS,
+		NetBIOS:          p.NetBIOS,
+		HTTP:             p.HTTP,
+		TLS:              p.TLS,
+		RTSP:             p.RTSP,
+		SSH:              p.SSH,
+		OSFingerprint:    p.OS,
+		SSDP:             p.SSDP,
+	}
+	if p.SNMP {
+		opts.SNMP = DefaultSNMPCredentials()
+	}
+	return opts
+}
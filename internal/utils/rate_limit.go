@@ -0,0 +1,155 @@
+package scan
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// ----------------------- límites de velocidad (cortesía) -------------------------
+
+// rateLimiter reparte turnos a intervalo fijo (1/rate por segundo), sin ráfagas:
+// cada wait reserva el siguiente turno libre y espera hasta que llegue.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter devuelve nil (sin límite) si perSec <= 0
+func newRateLimiter(perSec int) *rateLimiter {
+	if perSec <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Second / time.Duration(perSec)}
+}
+
+// wait bloquea hasta el turno reservado o hasta que se cancele ctx
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	d := time.Until(at)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// scanThrottle aplica el límite global de sondas por segundo y el límite por
+// subred (/24 en IPv4, /64 en IPv6) a cada paquete o conexión del escaneo.
+type scanThrottle struct {
+	global    *rateLimiter
+	perSubnet int
+
+	mu      sync.Mutex
+	subnets map[netip.Prefix]*rateLimiter
+}
+
+// newScanThrottle devuelve nil si no hay ningún límite configurado
+func newScanThrottle(rate, subnetRate int) *scanThrottle {
+	if rate <= 0 && subnetRate <= 0 {
+		return nil
+	}
+	return &scanThrottle{
+		global:    newRateLimiter(rate),
+		perSubnet: subnetRate,
+		subnets:   map[netip.Prefix]*rateLimiter{},
+	}
+}
+
+// wait espera el turno de una sonda hacia ip: primero en su subred y luego en
+// el límite global, para que una subred lenta no acapare turnos globales
+func (t *scanThrottle) wait(ctx context.Context, ip string) error {
+	if t == nil {
+		return ctx.Err()
+	}
+	if err := t.subnet(ip).wait(ctx); err != nil {
+		return err
+	}
+	return t.global.wait(ctx)
+}
+
+func (t *scanThrottle) subnet(ip string) *rateLimiter {
+	if t.perSubnet <= 0 {
+		return nil
+	}
+	addr, err := netip.ParseAddr(stripZone(ip))
+	if err != nil {
+		return nil
+	}
+	bits := 64
+	if addr.Is4() {
+		bits = 24
+	}
+	key, _ := addr.Prefix(bits)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.subnets[key]
+	if !ok {
+		l = newRateLimiter(t.perSubnet)
+		t.subnets[key] = l
+	}
+	return l
+}
+
+// ----------------------- conexiones en curso -------------------------
+
+// cotas del tope de conexiones simultáneas de todo el escaneo
+const (
+	minInFlightDials = hostPortWorkers
+	maxInFlightDials = 4096
+)
+
+// dialLimiter tope de sockets abiertos a la vez en todo el escaneo: cada host
+// prueba hasta hostPortWorkers puertos en paralelo y sin este tope Concurrency
+// hosts agotarían los descriptores del proceso. nil = sin tope.
+type dialLimiter chan struct{}
+
+// newDialLimiter dos conexiones por host en paralelo en promedio, entre
+// minInFlightDials y maxInFlightDials y sin pasar de la mitad del límite de
+// archivos abiertos del proceso (la otra mitad queda para pings, ARP, etc.)
+func newDialLimiter(concurrency int) dialLimiter {
+	n := min(max(2*concurrency, minInFlightDials), maxInFlightDials)
+	if limit := openFileLimit(); limit > 0 {
+		n = min(n, max(limit/2, 1))
+	}
+	return make(dialLimiter, n)
+}
+
+// acquire espera un lugar libre; false si se canceló ctx
+func (l dialLimiter) acquire(ctx context.Context) bool {
+	if l == nil {
+		return ctx.Err() == nil
+	}
+	select {
+	case l <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (l dialLimiter) release() {
+	if l != nil {
+		<-l
+	}
+}
@@ -0,0 +1,240 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"escaner/internal/models"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ----------------------- sondeo RTSP (cámaras y NVR) -------------------------
+
+// puertos donde se busca un servidor RTSP
+var rtspPorts = []int{554, 8554}
+
+// tamaño máximo del SDP que se lee
+const maxRTSPBody = 64 << 10
+
+// rtspVendors fabricantes reconocibles en Server, realm o SDP. Un modelo con el
+// formato propio de la marca también la identifica (muchas cámaras solo lo
+// muestran en el realm).
+var rtspVendors = []struct {
+	vendor string
+	re     *regexp.Regexp
+	model  *regexp.Regexp
+}{
+	{"Hikvision", regexp.MustCompile(`(?i)hikvision|\bhik(?:connect)?\b`), regexp.MustCompile(`\b(i?DS-\d[0-9A-Z]+(?:-[0-9A-Z/()]+)*)`)},
+	// Dahua pide digest con realm "Login to <serie>"
+	{"Dahua", regexp.MustCompile(`(?im)dahua|^login to [0-9a-z]+$`), regexp.MustCompile(`\b((?:DH-)?(?:IPC|NVR|XVR|HCVR)-?[0-9A-Z]+(?:-[0-9A-Z]+)*)`)},
+	// Axis usa realm "AXIS_<MAC>"
+	{"Axis", regexp.MustCompile(`(?i)\baxis\b|AXIS_[0-9A-F]{12}`), regexp.MustCompile(`\bAXIS ([A-Z]\d{4}(?:-[0-9A-Z]+)*)`)},
+	{"Ubiquiti", regexp.MustCompile(`(?i)ubnt|ubiquiti|unifi`), regexp.MustCompile(`\b(UVC[- ][0-9A-Za-z-]+)`)},
+	{"Reolink", regexp.MustCompile(`(?i)reolink`), regexp.MustCompile(`\b(RL[CN]-\d+[0-9A-Z-]*)`)},
+	{"Hanwha", regexp.MustCompile(`(?i)hanwha|wisenet|samsung techwin`), regexp.MustCompile(`\b([XPQ]N[ODVBF]-\d[0-9A-Z]+(?:-[0-9A-Z]+)*)`)},
+	{"Uniview", regexp.MustCompile(`(?i)uniview|\bunv\b`), regexp.MustCompile(`\b(IPC\d{3,}[0-9A-Z-]*)`)},
+	{"Vivotek", regexp.MustCompile(`(?i)vivotek`), regexp.MustCompile(`\b((?:FD|IB|IP|FE|MD|IT)\d{4}[0-9A-Z-]*)`)},
+	{"TP-Link", regexp.MustCompile(`(?i)tp-?link|\btapo\b`), regexp.MustCompile(`\b(Tapo C\d+)`)},
+	{"Foscam", regexp.MustCompile(`(?i)foscam`), nil},
+	{"Amcrest", regexp.MustCompile(`(?i)amcrest`), nil},
+	{"Mobotix", regexp.MustCompile(`(?i)mobotix`), nil},
+	{"Bosch", regexp.MustCompile(`(?i)\bbosch\b`), nil},
+}
+
+// rtspCameraHint Server o realm típicos de cámaras sin marca reconocible
+var rtspCameraHint = regexp.MustCompile(`(?i)camera|\bcam\b|webcam|netcam|\bipc\b|surveillance|\bdvs\b`)
+
+// rtspIsCamera la respuesta tiene evidencia de cámara: un fabricante de
+// rtspVendors, un stream de video en el SDP o un Server/realm de cámara.
+// Un servidor RTSP sin nada de eso puede ser un servidor de medios cualquiera.
+func rtspIsCamera(info models.RTSPInfo) bool {
+	if info.Vendor != "" || rtspCameraHint.MatchString(info.Server+" "+info.Realm) {
+		return true
+	}
+	return slices.ContainsFunc(info.Media, func(m string) bool { return strings.HasPrefix(m, "video") })
+}
+
+// payload types estáticos de RTP (RFC 3551) que no necesitan a=rtpmap
+var rtpStaticCodecs = map[string]string{"0": "PCMU", "8": "PCMA", "14": "MPA", "26": "JPEG", "32": "MPV", "33": "MP2T"}
+
+// inspectRTSP sondea los puertos RTSP conocidos que están en ports y los que las
+// sondas de servicio identificaron como RTSP
+func inspectRTSP(s *hostSession, ports []int, services []models.Service) []models.RTSPInfo {
+	var candidates []int
+	for _, p := range rtspPorts {
+		if slices.Contains(ports, p) {
+			candidates = append(candidates, p)
+		}
+	}
+	for _, svc := range services {
+		if svc.Protocol == "tcp" && svc.Name == "rtsp" && !slices.Contains(candidates, svc.Port) {
+			candidates = append(candidates, svc.Port)
+		}
+	}
+	var out []models.RTSPInfo
+	for _, p := range candidates {
+		if info, ok := s.rtspInfo(p); ok {
+			out = append(out, info)
+		}
+	}
+	return out
+}
+
+// rtspProbe envía OPTIONS y DESCRIBE por la misma conexión. Los servidores RTSP
+// no mandan banner: solo contestan pedidos. OPTIONS suele responder sin
+// credenciales (Server); DESCRIBE devuelve el SDP o un 401 con el realm.
+func rtspProbe(ctx context.Context, ip string, port int, timeout time.Duration) (models.RTSPInfo, bool) {
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil {
+		return models.RTSPInfo{}, false
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	url := "rtsp://" + net.JoinHostPort(stripZone(ip), strconv.Itoa(port)) + "/"
+	r := bufio.NewReader(conn)
+	info := models.RTSPInfo{Port: port}
+
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	status, hdr, _, err := rtspRequest(conn, r, "OPTIONS", url, 1)
+	if err != nil {
+		return models.RTSPInfo{}, false
+	}
+	info.Status = status
+	info.Server = hdr.Get("Server")
+	info.Realm = rtspRealm(hdr)
+
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	if status, hdr, body, err := rtspRequest(conn, r, "DESCRIBE", url, 2); err == nil {
+		info.Status = status
+		if info.Server == "" {
+			info.Server = hdr.Get("Server")
+		}
+		if realm := rtspRealm(hdr); realm != "" {
+			info.Realm = realm
+		}
+		if strings.Contains(strings.ToLower(hdr.Get("Content-Type")), "sdp") {
+			parseSDP(body, &info)
+		}
+	}
+	info.Vendor, info.Model = rtspVendorModel(info)
+	return info, true
+}
+
+// rtspRequest envía un pedido y lee la respuesta (línea de estado, headers y cuerpo)
+func rtspRequest(conn net.Conn, r *bufio.Reader, method, url string, cseq int) (int, textproto.MIMEHeader, string, error) {
+	req := fmt.Sprintf("%s %s RTSP/1.0\r\nCSeq: %d\r\nUser-Agent: scan\r\n", method, url, cseq)
+	if method == "DESCRIBE" {
+		req += "Accept: application/sdp\r\n"
+	}
+	if _, err := conn.Write([]byte(req + "\r\n")); err != nil {
+		return 0, nil, "", err
+	}
+	tp := textproto.NewReader(r)
+	line, err := tp.ReadLine()
+	if err != nil {
+		return 0, nil, "", err
+	}
+	proto, rest, _ := strings.Cut(line, " ")
+	code, _, _ := strings.Cut(rest, " ")
+	status, err := strconv.Atoi(code)
+	if !strings.HasPrefix(proto, "RTSP/") || err != nil {
+		return 0, nil, "", fmt.Errorf("respuesta RTSP inválida: %q", line)
+	}
+	hdr, err := tp.ReadMIMEHeader()
+	if err != nil && len(hdr) == 0 {
+		return 0, nil, "", err
+	}
+	var body []byte
+	if n, err := strconv.Atoi(hdr.Get("Content-Length")); err == nil && n > 0 {
+		body = make([]byte, min(n, maxRTSPBody))
+		n, _ := io.ReadFull(r, body)
+		body = body[:n]
+	}
+	return status, hdr, string(body), nil
+}
+
+func rtspRealm(hdr textproto.MIMEHeader) string {
+	for _, h := range hdr.Values("WWW-Authenticate") {
+		if m := realmRe.FindStringSubmatch(h); m != nil {
+			return strings.TrimSpace(m[1])
+		}
+	}
+	return ""
+}
+
+// parseSDP toma de la descripción (RFC 8866) el nombre de sesión, a=tool y los
+// medios con su primer códec
+func parseSDP(sdp string, info *models.RTSPInfo) {
+	var session []string
+	codecs := map[string]string{}
+	type media struct{ kind, pt string }
+	var medias []media
+	for _, line := range strings.Split(sdp, "\n") {
+		line = strings.TrimSpace(line)
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "s", "i":
+			if v := strings.TrimSpace(val); v != "" && v != "-" && len(medias) == 0 {
+				session = append(session, v)
+			}
+		case "a":
+			if tool, ok := strings.CutPrefix(val, "tool:"); ok && info.Tool == "" {
+				info.Tool = strings.TrimSpace(tool)
+			} else if m, ok := strings.CutPrefix(val, "rtpmap:"); ok {
+				pt, enc, _ := strings.Cut(m, " ")
+				codec, _, _ := strings.Cut(enc, "/")
+				codecs[pt] = codec
+			}
+		case "m":
+			// m=video 0 RTP/AVP 96
+			if f := strings.Fields(val); len(f) >= 4 {
+				medias = append(medias, media{f[0], f[3]})
+			}
+		}
+	}
+	info.Session = strings.Join(session, " ")
+	for _, m := range medias {
+		codec := codecs[m.pt]
+		if codec == "" {
+			codec = rtpStaticCodecs[m.pt]
+		}
+		info.Media = append(info.Media, strings.TrimSpace(m.kind+" "+codec))
+	}
+}
+
+// rtspVendorModel fabricante y modelo según Server, realm y SDP
+func rtspVendorModel(info models.RTSPInfo) (string, string) {
+	text := strings.Join([]string{info.Server, info.Realm, info.Session, info.Tool}, "\n")
+	for _, v := range rtspVendors {
+		var model string
+		if v.model != nil {
+			if m := v.model.FindStringSubmatch(text); m != nil {
+				model = m[1]
+			}
+		}
+		if model != "" || v.re.MatchString(text) {
+			return v.vendor, model
+		}
+	}
+	return "", ""
+}
+
+// rtspText "RTSP/1.0 estado Server realm sesión tool fabricante modelo medios":
+// el texto que miran las reglas rtsp
+func rtspText(info models.RTSPInfo) string {
+	parts := []string{"RTSP/1.0 " + strconv.Itoa(info.Status), info.Server, info.Realm, info.Session, info.Tool, info.Vendor, info.Model}
+	return strings.Join(strings.Fields(strings.Join(append(parts, info.Media...), " ")), " ")
+}
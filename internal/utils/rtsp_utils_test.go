@@ -0,0 +1,130 @@
+package scan
+
+import (
+	"escaner/internal/models"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// SDP de un DESCRIBE a una cámara Hikvision (LF)
+const sdpHikvision = `v=0
+o=- 1109162014219182 1109162014219192 IN IP4 192.168.1.64
+s=Media Presentation
+e=NONE
+b=AS:5050
+t=0 0
+a=control:rtsp://192.168.1.64:554/Streaming/Channels/101/
+m=video 0 RTP/AVP 96
+c=IN IP4 0.0.0.0
+b=AS:5000
+a=recvonly
+a=x-dimensions:1920,1080
+a=control:rtsp://192.168.1.64:554/Streaming/Channels/101/trackID=1
+a=rtpmap:96 H264/90000
+a=fmtp:96 profile-level-id=420029; packetization-mode=1; sprop-parameter-sets=Z00AKpWoHgCJ+WbgICAgQA==,aO48gA==
+m=audio 0 RTP/AVP 8
+c=IN IP4 0.0.0.0
+b=AS:50
+a=recvonly
+a=control:rtsp://192.168.1.64:554/Streaming/Channels/101/trackID=2
+a=rtpmap:8 PCMA/8000
+a=Media_header:MEDIAINFO=494D4B48010100000400010010710110401F000000FA000000000000000000000000000000000000;
+a=appversion:1.0
+`
+
+// SDP de un NVR Dahua (CRLF, audio con payload estático sin a=rtpmap)
+var sdpDahua = strings.ReplaceAll(`v=0
+o=- 2251938216 2251938216 IN IP4 0.0.0.0
+s=Media Server
+c=IN IP4 0.0.0.0
+t=0 0
+a=control:*
+a=packetization-supported:DH
+a=range:npt=now-
+m=video 0 RTP/AVP 96
+a=control:trackID=0
+a=framerate:25.000000
+a=rtpmap:96 H265/90000
+a=recvonly
+m=audio 0 RTP/AVP 8
+a=control:trackID=1
+a=recvonly
+`, "\n", "\r\n")
+
+// SDP de live555 sirviendo un archivo: servidor de medios, no cámara
+const sdpLive555 = `v=0
+o=- 1604417224 1 IN IP4 10.0.0.5
+s=Session streamed by "testOnDemandRTSPServer"
+i=movie.mkv
+t=0 0
+a=tool:LIVE555 Streaming Media v2020.10.16
+a=type:broadcast
+a=control:*
+a=range:npt=0-5400.000
+m=audio 0 RTP/AVP 97
+c=IN IP4 0.0.0.0
+a=rtpmap:97 MPEG4-GENERIC/48000/2
+a=control:track1
+m=video 0 RTP/AVP 26
+a=control:track2
+`
+
+func TestParseSDP(t *testing.T) {
+	cases := []struct {
+		name string
+		sdp  string
+		want models.RTSPInfo
+	}{
+		{"Hikvision", sdpHikvision, models.RTSPInfo{Session: "Media Presentation", Media: []string{"video H264", "audio PCMA"}}},
+		{"Dahua CRLF", sdpDahua, models.RTSPInfo{Session: "Media Server", Media: []string{"video H265", "audio PCMA"}}},
+		{"live555", sdpLive555, models.RTSPInfo{
+			Session: `Session streamed by "testOnDemandRTSPServer" movie.mkv`,
+			Tool:    "LIVE555 Streaming Media v2020.10.16",
+			Media:   []string{"audio MPEG4-GENERIC", "video JPEG"},
+		}},
+		{"GStreamer con s=- y a=tool repetido", "v=0\ns=-\ni=rtsp-server\na=tool:GStreamer\na=tool:otro\nm=video 0 RTP/AVP 96\na=rtpmap:96 H264/90000\n",
+			models.RTSPInfo{Session: "rtsp-server", Tool: "GStreamer", Media: []string{"video H264"}}},
+		{"s= de un medio no es la sesión", "s=Cam\nm=video 0 RTP/AVP 96\ni=pista 1\n",
+			models.RTSPInfo{Session: "Cam", Media: []string{"video"}}},
+		{"truncado a mitad de línea", sdpHikvision[:strings.Index(sdpHikvision, "Presentation")+4],
+			models.RTSPInfo{Session: "Media Pres"}},
+		{"truncado en el m=", "s=Cam\nm=video 0 RTP/AVP", models.RTSPInfo{Session: "Cam"}},
+		{"rtpmap sin códec", "m=video 0 RTP/AVP 96\na=rtpmap:96\n", models.RTSPInfo{Media: []string{"video"}}},
+		{"basura", "HTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\n<html>", models.RTSPInfo{}},
+		{"vacío", "", models.RTSPInfo{}},
+	}
+	for _, c := range cases {
+		var got models.RTSPInfo
+		parseSDP(c.sdp, &got)
+		if got.Session != c.want.Session || got.Tool != c.want.Tool || !slices.Equal(got.Media, c.want.Media) {
+			t.Errorf("%s: parseSDP = %+v, quiero %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRTSPVendorModel(t *testing.T) {
+	cases := []struct {
+		name          string
+		info          models.RTSPInfo
+		vendor, model string
+	}{
+		{"modelo Hikvision en el realm", models.RTSPInfo{Realm: "DS-2CD2143G0-I"}, "Hikvision", "DS-2CD2143G0-I"},
+		{"realm Dahua con la serie", models.RTSPInfo{Server: "Rtsp Server/3.0", Realm: "Login to 4K0123PAZ12345"}, "Dahua", ""},
+		{"modelo Dahua", models.RTSPInfo{Realm: "IPC-HDW2431T-AS"}, "Dahua", "IPC-HDW2431T-AS"},
+		{"realm Axis con la MAC", models.RTSPInfo{Realm: "AXIS_ACCC8E012345"}, "Axis", ""},
+		{"modelo Axis en la sesión", models.RTSPInfo{Session: "AXIS P3245-LV Network Camera", Tool: "GStreamer"}, "Axis", "P3245-LV"},
+		{"Tapo", models.RTSPInfo{Server: "Tapo C200 RTSP"}, "TP-Link", "Tapo C200"},
+		{"Reolink", models.RTSPInfo{Session: "Reolink RLC-510A"}, "Reolink", "RLC-510A"},
+		{"Ubiquiti sin modelo", models.RTSPInfo{Server: "UBNT Streaming Server v1.2"}, "Ubiquiti", ""},
+		{"servidor de medios", models.RTSPInfo{Session: "Session streamed by \"testOnDemandRTSPServer\"", Tool: "LIVE555 Streaming Media v2020.10.16"}, "", ""},
+		{"realm genérico", models.RTSPInfo{Realm: "Login to the server"}, "", ""},
+		{"vacío", models.RTSPInfo{}, "", ""},
+	}
+	for _, c := range cases {
+		vendor, model := rtspVendorModel(c.info)
+		if vendor != c.vendor || model != c.model {
+			t.Errorf("%s: rtspVendorModel = %q %q, quiero %q %q", c.name, vendor, model, c.vendor, c.model)
+		}
+	}
+}
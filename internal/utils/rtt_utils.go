@@ -0,0 +1,90 @@
+package scan
+
+import (
+	"sync"
+	"time"
+)
+
+// ----------------------- timeouts adaptativos -------------------------
+
+const (
+	// rttGranularity piso de la varianza en el RTO (G en RFC 6298)
+	rttGranularity = 10 * time.Millisecond
+	// minConnectTimeout ni en la LAN más rápida se espera menos un SYN-ACK o un echo reply
+	minConnectTimeout = 100 * time.Millisecond
+)
+
+// rttEstimator RTT suavizado de un host según RFC 6298. Cada ping respondido y
+// cada conexión TCP aceptada o rechazada (el RST también es una ida y vuelta)
+// aporta una muestra.
+type rttEstimator struct {
+	mu     sync.Mutex
+	srtt   time.Duration
+	rttvar time.Duration
+	n      int
+}
+
+func (e *rttEstimator) observe(d time.Duration) {
+	if d <= 0 {
+		// el comando ping no siempre informa el tiempo
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.n == 0 {
+		e.srtt, e.rttvar = d, d/2
+	} else {
+		diff := e.srtt - d
+		if diff < 0 {
+			diff = -diff
+		}
+		e.rttvar = (3*e.rttvar + diff) / 4
+		e.srtt = (7*e.srtt + d) / 8
+	}
+	e.n++
+}
+
+// rto espera razonable para una ida y vuelta; false si todavía no hay muestras
+func (e *rttEstimator) rto() (time.Duration, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.n == 0 {
+		return 0, false
+	}
+	return e.srtt + max(4*e.rttvar, rttGranularity), true
+}
+
+// connectTimeout espera de un ping o connect: el RTO del host, duplicado en cada
+// reintento y sin pasar de maxTimeout. Sin mediciones (o sin Adaptive) es Timeout.
+func (s *hostSession) connectTimeout(attempt int) time.Duration {
+	rto, ok := s.rtt.rto()
+	if !s.adaptive || !ok {
+		return s.timeout
+	}
+	return min(max(rto, minConnectTimeout)<<attempt, s.maxTimeout)
+}
+
+// probeTimeout espera de una sonda que necesita rtts idas y vueltas (connect,
+// handshake, pedido). Timeout queda para lo que tarda el servidor en contestar y
+// en enlaces lentos se suman las idas y vueltas medidas, hasta maxTimeout.
+func (s *hostSession) probeTimeout(rtts int) time.Duration {
+	rto, ok := s.rtt.rto()
+	if !s.adaptive || !ok {
+		return s.timeout
+	}
+	return min(s.timeout+time.Duration(rtts)*rto, s.maxTimeout)
+}
+
+// ping ICMP echo con reintentos; la respuesta alimenta la estimación de RTT
+func (s *hostSession) ping() (pingReply, bool) {
+	for attempt := 0; attempt <= s.retries; attempt++ {
+		if s.throttle.wait(s.ctx, s.ip) != nil {
+			break
+		}
+		if reply, ok := tryPing(s.ctx, s.ip, s.connectTimeout(attempt)); ok {
+			s.rtt.observe(reply.RTT)
+			return reply, true
+		}
+	}
+	return pingReply{}, false
+}
@@ -1,118 +1,312 @@
 package scan
 
 import (
-	"bufio"
+	"context"
 	"escaner/internal/models"
 	"fmt"
+	"iter"
 	"net"
-	"net/http"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
 )
 
 // ----------------------- utilidad de parsing y rangos -------------------------
 
+// ExpandArgToIPs expande un argumento de objetivos a la lista completa de IPs
+// (incluye red y broadcast). Para rangos grandes usar ParseTargets, que recorre
+// las direcciones sin guardarlas en memoria.
 func ExpandArgToIPs(arg string) ([]string, error) {
-	arg = strings.TrimSpace(arg)
-	// CIDR
-	if strings.Contains(arg, "/") {
-		_, ipnet, err := net.ParseCIDR(arg)
-		if err != nil {
-			return nil, err
-		}
-		return ipsFromCIDR(ipnet), nil
-	}
-	// shorthand last-octet e.g., 192.168.181.1-255
-	if strings.Count(arg, "-") == 1 && !strings.Contains(arg, " ") {
-		parts := strings.Split(arg, "-")
-		left := parts[0]
-		right := parts[1]
-		if net.ParseIP(left) != nil {
-			ip4 := net.ParseIP(left).To4()
-			if ip4 != nil {
-				if hi, err := strconv.Atoi(right); err == nil {
-					if hi >= 0 && hi <= 255 {
-						base := fmt.Sprintf("%d.%d.%d", ip4[0], ip4[1], ip4[2])
-						lo := int(ip4[3])
-						if lo > hi {
-							lo, hi = hi, lo
-						}
-						out := make([]string, 0, hi-lo+1)
-						for i := lo; i <= hi; i++ {
-							out = append(out, fmt.Sprintf("%s.%d", base, i))
-						}
-						return out, nil
-					}
-				}
-			}
-		}
-		// fallthrough to full-range parsing
-	}
-	// full range: 192.168.1.10-192.168.1.50
-	if strings.Count(arg, "-") == 1 {
-		parts := strings.Split(arg, "-")
-		a := net.ParseIP(strings.TrimSpace(parts[0]))
-		b := net.ParseIP(strings.TrimSpace(parts[1]))
-		if a == nil || b == nil {
-			return nil, fmt.Errorf("rango ip inválido")
-		}
-		return ipsFromRange(a, b)
-	}
-	// single IP
-	if net.ParseIP(arg) != nil {
-		return []string{arg}, nil
+	t := &Targets{KeepNetBcast: true}
+	if err := t.Add(arg); err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("formato de IP no reconocido")
+	return slices.Collect(t.All()), nil
 }
 
 // ----------------------- función reutilizable de escaneo -------------------------
 
-// scanIPs realiza el escaneo paralelo de la lista de IPs usando las mismas heurísticas
+// hostPortWorkers conexiones TCP simultáneas por host en modo AllPorts
+const hostPortWorkers = 64
+
+// ScanOptions parámetros de un escaneo
+type ScanOptions struct {
+	Ports []int // puertos TCP
+	// UDPPorts puertos UDP que se sondean con payloads propios de cada protocolo
+	// (ver ParsePortSpec); una respuesta válida cuenta como host vivo
+	UDPPorts    []int
+	Timeout     time.Duration
+	Concurrency int
+	// Retries reintentos del ping, de cada conexión TCP y de cada sonda UDP que no
+	// tuvo respuesta (un puerto que rechaza la conexión no se reintenta)
+	Retries int
+	// AdaptiveTimeout ajusta las esperas de cada host a su RTT medido: Timeout
+	// vale hasta la primera medición y después los connects esperan un RTO y las
+	// sondas Timeout más sus idas y vueltas. MaxTimeout es el tope (0 = 4×Timeout).
+	AdaptiveTimeout bool
+	MaxTimeout      time.Duration
+	// AllPorts prueba todos los puertos de Ports y UDPPorts y los reporta en
+	// Result.OpenPorts y Result.OpenUDPPorts,
+	// en vez de detenerse en el primero que responde
+	AllPorts bool
+	// ServiceDetection identifica servicio y versión en cada puerto abierto
+	// usando la base de sondas (ver LoadServiceProbes)
+	ServiceDetection bool
+	// Rate máximo de sondas por segundo en todo el escaneo (ping, conexiones TCP,
+	// banners, HTTP); 0 = sin límite
+	Rate int
+	// SubnetRate máximo de sondas por segundo hacia una misma /24 (/64 en IPv6);
+	// 0 = sin límite
+	SubnetRate int
+	// MDNS consulta por multicast DNS los servicios anunciados en la red local
+	// (nombres, modelos y tipos de servicio); también marca vivos a los equipos
+	// que solo responden mDNS
+	MDNS bool
+	// NetBIOS consulta la tabla de nombres NetBIOS (UDP 137) de cada host vivo
+	// para obtener nombre de equipo, grupo de trabajo y MAC
+	NetBIOS bool
+	// HTTP toma la huella de cada puerto web abierto por HTTP y HTTPS: título,
+	// Server, realm, meta generator y hash del favicon, siguiendo redirecciones
+	HTTP bool
+	// TLS inspecciona el certificado de los puertos TLS abiertos (443, 8443...):
+	// sujeto, SANs, emisor, vigencia y tipo de clave
+	TLS bool
+	// RTSP envía OPTIONS/DESCRIBE a los puertos RTSP abiertos (554, 8554) y
+	// extrae Server, realm, SDP, fabricante y modelo de cámaras y NVR
+	RTSP bool
+	// SSH interpreta la identificación de los puertos SSH abiertos (22, 2222):
+	// sistema, distribución, release y familia de equipo (Dropbear, RouterOS...)
+	SSH bool
+	// OSFingerprint deduce la familia de sistema del host (Windows, Linux,
+	// BSD/macOS, RTOS embebido, sistema de red) del TTL y del SYN-ACK de un
+	// puerto abierto; en Linux captura el SYN-ACK con un socket crudo
+	OSFingerprint bool
+	// SSDP busca equipos UPnP (TVs, NAS, routers, reproductores) con M-SEARCH y
+	// lee su descripción; también marca vivos a los que solo responden SSDP
+	SSDP bool
+	// SNMP consulta sysDescr, sysObjectID y sysName (UDP 161) de cada host vivo
+	// con estas credenciales; nil = no se consulta SNMP
+	SNMP *SNMPCredentials
+}
+
+// ScanIPs realiza el escaneo paralelo de los objetivos usando las mismas heurísticas.
+// targets se consume a medida que hay workers libres, así que la memoria no depende
+// del tamaño del rango: devuelve los hosts vivos ordenados por IP y solo la
+// cantidad de objetivos que no respondieron.
+// Si ctx se cancela deja de lanzar hosts nuevos, aborta las sondas en curso y
+// devuelve solo los resultados completados hasta ese momento.
 func ScanIPs(
-	ips []string,
-	ports []int,
-	timeout time.Duration,
-	concurrency int,
+	ctx context.Context,
+	targets iter.Seq[string],
+	opts ScanOptions,
 	onAlive func(models.Result), // nuevo parámetro
-) []models.Result {
+) (results []models.Result, down int) {
+	ports, timeout := opts.Ports, opts.Timeout
+	concurrency := max(opts.Concurrency, 1)
+
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, concurrency)
-	resultsCh := make(chan models.Result, len(ips))
+	resultsCh := make(chan models.Result, concurrency)
+	throttle := newScanThrottle(opts.Rate, opts.SubnetRate)
+	dials := newDialLimiter(concurrency)
+	var mdns *mdnsDiscovery
+	if opts.MDNS {
+		mdns = startMDNSDiscovery(ctx, max(2*timeout, time.Second), throttle)
+	}
+	var ssdp *ssdpDiscovery
+	if opts.SSDP {
+		ssdp = startSSDPDiscovery(ctx, max(2*timeout, time.Second), timeout, throttle)
+	}
+
+	// los muertos solo se cuentan: guardarlos haría crecer la memoria con el rango
+	var deadCount atomic.Int64
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for r := range resultsCh {
+			results = append(results, r)
+		}
+	}()
 
-	for _, ip := range ips {
+loop:
+	for ip := range targets {
+		select {
+		case <-ctx.Done():
+			break loop
+		case sem <- struct{}{}:
+		}
 		wg.Add(1)
-		sem <- struct{}{}
 		go func(ip string) {
 			defer wg.Done()
 			defer func() { <-sem }()
 
 			res := models.Result{IP: ip}
-			if tryPing(ip, timeout) {
+			// sondas TCP/HTTP de este host compartidas por todas las etapas
+			sess := newHostSession(ctx, ip, opts, throttle, dials)
+			if reply, ok := sess.ping(); ok {
 				res.Alive = true
 				res.Method = "icmp"
-			} else {
+				res.RTTMs = float64(reply.RTT.Microseconds()) / 1000
+				res.TTL = reply.TTL
+			}
+			if opts.AllPorts {
+				// enumerar todos los puertos aunque el host ya respondió al ping
+				res.OpenPorts = sess.openPorts(ports)
+				res.OpenUDPPorts = sess.openUDPPorts(opts.UDPPorts)
+				if !res.Alive && len(res.OpenPorts) > 0 {
+					res.Alive = true
+					res.Method = "tcp"
+					res.Port = res.OpenPorts[0]
+				} else if !res.Alive && len(res.OpenUDPPorts) > 0 {
+					res.Alive = true
+					res.Method = "udp"
+					res.Port = res.OpenUDPPorts[0]
+				}
+			} else if !res.Alive {
 				for _, p := range ports {
-					if tryTCP(ip, p, timeout) {
+					if sess.isOpen(p) {
 						res.Alive = true
 						res.Method = "tcp"
 						res.Port = p
 						break
 					}
 				}
+				// solo servicios UDP (DNS, SNMP, NTP...): las sondas van en paralelo
+				// para que un host muerto cueste un único timeout
+				if !res.Alive {
+					if open := sess.openUDPPorts(opts.UDPPorts); len(open) > 0 {
+						res.Alive = true
+						res.Method = "udp"
+						res.Port = open[0]
+					}
+				}
+			}
+
+			// muchos celulares no responden ping ni tienen puertos abiertos, pero sí mDNS
+			announced, hasMDNS := mdns.lookup(ctx, ip)
+			if !res.Alive && hasMDNS {
+				res.Alive = true
+				res.Method = "mdns"
+			}
+			upnp, hasUPnP := ssdp.lookup(ctx, ip)
+			if !res.Alive && hasUPnP {
+				res.Alive = true
+				res.Method = "ssdp"
+			}
+
+			// a los hosts muertos no se les gastan más sondas
+			if !res.Alive {
+				if ctx.Err() == nil {
+					deadCount.Add(1)
+				}
+				return
+			}
+			if hasMDNS {
+				res.Hostname = announced.Hostname
+				res.Model = announced.Model
+				res.MDNSServices = announced.Services
+			}
+			if hasUPnP {
+				res.UPnP = &upnp
+				if res.Model == "" {
+					res.Model = upnp.ModelName
+				}
+			}
+
+			if opts.ServiceDetection {
+				if res.OpenPorts == nil {
+					res.OpenPorts = sess.openPorts(ports)
+				}
+				if res.OpenUDPPorts == nil {
+					res.OpenUDPPorts = sess.openUDPPorts(opts.UDPPorts)
+				}
+				res.Services = detectServices(sess, res.OpenPorts, res.OpenUDPPorts)
+			}
+			if opts.TLS {
+				res.TLS = inspectTLS(sess, append(slices.Clone(ports), res.OpenPorts...))
+			}
+			if opts.HTTP {
+				res.HTTP = fingerprintWeb(sess, append(slices.Clone(ports), res.OpenPorts...), res.Services)
+			}
+			if opts.SSH {
+				res.SSH = inspectSSH(sess, append(slices.Clone(ports), res.OpenPorts...), res.Services)
+			}
+			if opts.RTSP {
+				res.RTSP = inspectRTSP(sess, append(slices.Clone(ports), res.OpenPorts...), res.Services)
+				for _, r := range res.RTSP {
+					if res.Model == "" && r.Model != "" {
+						res.Model = r.Model
+					}
+				}
 			}
 
-			mac, _ := getMAC(ip, timeout)
+			// el ping, las conexiones TCP y las sondas UDP ya resolvieron el ARP;
+			// los descubiertos por mDNS/SSDP todavía no recibieron nada nuestro
+			unicast := res.Method == "icmp" || res.Method == "tcp" || res.Method == "udp"
+			mac, _ := getMAC(ctx, ip, sess.connectTimeout(0), throttle, !unicast)
 			res.MAC = mac
-			if names, err := net.LookupAddr(ip); err == nil && len(names) > 0 {
+			if opts.NetBIOS && throttle.wait(ctx, ip) == nil {
+				if nb, ok := nbstatQuery(ctx, ip, sess.probeTimeout(1)); ok {
+					res.NetBIOSName = nb.Name
+					res.Workgroup = nb.Workgroup
+					if res.MAC == "" {
+						// fuera del segmento local la MAC de NBSTAT es la única que tenemos
+						res.MAC = nb.MAC
+					}
+				}
+			}
+			if opts.SNMP != nil {
+				if info, ok := snmpQuery(ctx, ip, sess.probeTimeout(2), opts.SNMP, throttle); ok {
+					res.SNMP = &info
+				}
+			}
+			res.Vendor = LookupVendor(res.MAC)
+			if names, err := net.DefaultResolver.LookupAddr(ctx, stripZone(ip)); err == nil && len(names) > 0 {
 				res.ReverseDNS = strings.TrimSuffix(names[0], ".")
 			}
+			// sin PTR: nombre NetBIOS (Windows), el anunciado por mDNS, sysName, el
+			// nombre UPnP o el del certificado TLS
+			if res.ReverseDNS == "" {
+				res.ReverseDNS = res.NetBIOSName
+			}
+			if res.ReverseDNS == "" {
+				res.ReverseDNS = res.Hostname
+			}
+			if res.ReverseDNS == "" && res.SNMP != nil {
+				res.ReverseDNS = res.SNMP.SysName
+			}
+			if res.ReverseDNS == "" && res.UPnP != nil {
+				res.ReverseDNS = res.UPnP.FriendlyName
+			}
+			if res.ReverseDNS == "" {
+				res.ReverseDNS = tlsNameHint(res.TLS)
+			}
+
+			if opts.OSFingerprint {
+				port := 0
+				if open := sess.knownOpen(); len(open) > 0 {
+					port = open[0]
+				}
+				res.OS = fingerprintOS(ctx, ip, port, res.TTL, sess.probeTimeout(1), throttle)
+			}
 
-			// primero detectar tipo (usa reverseDNS y MAC)
-			res.DeviceType = detectDeviceType(ip, ports, timeout, res.MAC, res.ReverseDNS)
+			// primero detectar tipo (usa reverseDNS, MAC, puertos, servicios y sistema)
+			res.DeviceType = detectDeviceType(sess, res)
+			if res.DeviceType == "Unknown" {
+				// la base de sondas puede reconocer el equipo (impresora por PJL, cámara por RTSP...)
+				for _, svc := range res.Services {
+					if svc.DeviceType != "" {
+						res.DeviceType = svc.DeviceType
+						break
+					}
+				}
+			}
 			if res.Alive && res.DeviceType == "Unknown" {
 				if brand, ok := isMobileOUI(res.MAC); ok {
 					res.DeviceType = "Mobile"
@@ -123,10 +317,15 @@ func ScanIPs(
 			}
 
 			// luego enriquecer name: si no hay reverseDNS intentamos HTTP/banner heuristics
-			res.ReverseDNS = enrichName(ip, timeout, res.ReverseDNS)
+			res.ReverseDNS = enrichName(sess, res.ReverseDNS)
+
+			// escaneo cancelado: el resultado quedó a medias, no lo reportamos
+			if ctx.Err() != nil {
+				return
+			}
 
 			// Llamamos al callback si está vivo
-			if res.Alive && onAlive != nil {
+			if onAlive != nil {
 				onAlive(res)
 			}
 
@@ -136,41 +335,51 @@ func ScanIPs(
 
 	wg.Wait()
 	close(resultsCh)
+	<-collected
 
-	var results []models.Result
-	for r := range resultsCh {
-		results = append(results, r)
-	}
 	sort.Slice(results, func(i, j int) bool {
 		return ipLess(results[i].IP, results[j].IP)
 	})
 
-	return results
+	return results, int(deadCount.Load())
+}
+
+// marcas de celulares: isMobileOUI las busca al inicio del fabricante del registro OUI
+var mobileVendors = []string{
+	"Apple",
+	"Samsung",
+	"Xiaomi",
+	"Huawei",
+	"Motorola",
+	"Honor",
+	"OnePlus",
+	"OPPO",
+	"vivo",
+	// puedes agregar más aquí
 }
 
-// /saber mobiles:
+// prefijos de celulares que el registro embebido no trae; se consultan solo si
+// LookupVendor no conoce el fabricante
 var mobileOUIs = map[string]string{
-	"c2:a1:6f": "Apple",
-	"d4:61:9d": "Samsung",
-	"f0:27:2d": "Xiaomi",
-	"44:65:0d": "Huawei",
-	"ac:bc:32": "Motorola",
-	"3486da":   "Honor",
+	"3486da": "Honor",
 	// puedes agregar más aquí
 }
 
 func isMobileOUI(mac string) (string, bool) {
-	if mac == "" {
-		return "", false
-	}
-	// normalizar: quitar separadores y pasar a minúsculas
-	cleaned := strings.ToLower(strings.ReplaceAll(strings.ReplaceAll(mac, ":", ""), "-", ""))
-	if len(cleaned) < 6 {
-		return "", false
+	vendor := strings.ToLower(LookupVendor(mac))
+	if vendor == "" {
+		// normalizar: quitar separadores y pasar a minúsculas
+		cleaned := strings.ToLower(strings.ReplaceAll(strings.ReplaceAll(mac, ":", ""), "-", ""))
+		if len(cleaned) < 6 {
+			return "", false
+		}
+		brand, ok := mobileOUIs[cleaned[:6]]
+		return brand, ok
 	}
-	prefix := cleaned[:6] // primeros 3 bytes
-	if brand, ok := mobileOUIs[prefix]; ok {
-		return brand, true
+	for _, brand := range mobileVendors {
+		if strings.HasPrefix(vendor, strings.ToLower(brand)) {
+			return brand, true
+		}
 	}
 	return "", false
 }
@@ -186,6 +395,12 @@ func FormatResult(r models.Result) string {
 			method = "ICMP"
 		} else if r.Method == "tcp" {
 			method = fmt.Sprintf("TCP/%d", r.Port)
+		} else if r.Method == "udp" {
+			method = fmt.Sprintf("UDP/%d", r.Port)
+		} else if r.Method == "mdns" {
+			method = "mDNS"
+		} else if r.Method == "ssdp" {
+			method = "SSDP"
 		}
 	}
 	mac := r.MAC
@@ -200,118 +415,160 @@ func FormatResult(r models.Result) string {
 	if dev == "" {
 		dev = "-"
 	}
-	return fmt.Sprintf("%-15s  alive:%-3s  via:%-10s  device:%-20s  mac:%-17s  name:%s",
+	line := fmt.Sprintf("%-15s  alive:%-3s  via:%-10s  device:%-20s  mac:%-17s  name:%s",
 		r.IP, alive, method, dev, mac, name)
+	if r.Vendor != "" {
+		line += "  vendor:" + r.Vendor
+	}
+	if r.Model != "" {
+		line += "  model:" + r.Model
+	}
+	if r.OS != nil {
+		line += fmt.Sprintf("  os:%s(%.0f%%)", r.OS.Family, r.OS.Confidence*100)
+	}
+	if len(r.OpenPorts) > 0 {
+		ps := make([]string, len(r.OpenPorts))
+		for i, p := range r.OpenPorts {
+			ps[i] = strconv.Itoa(p)
+		}
+		line += "  ports:" + strings.Join(ps, ",")
+	}
+	if len(r.OpenUDPPorts) > 0 {
+		ps := make([]string, len(r.OpenUDPPorts))
+		for i, p := range r.OpenUDPPorts {
+			ps[i] = strconv.Itoa(p)
+		}
+		line += "  udp:" + strings.Join(ps, ",")
+	}
+	for _, c := range r.TLS {
+		if c.Expired {
+			line += fmt.Sprintf("  cert:%d vencido", c.Port)
+		} else if c.ExpiresSoon {
+			line += fmt.Sprintf("  cert:%d vence %s", c.Port, c.NotAfter.Format("2006-01-02"))
+		}
+	}
+	if len(r.Services) > 0 {
+		svcs := make([]string, len(r.Services))
+		for i, svc := range r.Services {
+			svcs[i] = fmt.Sprintf("%d/%s", svc.Port, svc.Name)
+			if svc.Protocol == "udp" {
+				svcs[i] = fmt.Sprintf("%d/udp/%s", svc.Port, svc.Name)
+			}
+			if desc := strings.TrimSpace(svc.Product + " " + svc.Version); desc != "" {
+				svcs[i] += "(" + desc + ")"
+			}
+		}
+		line += "  svc:" + strings.Join(svcs, ",")
+	}
+	return line
 }
 
 // ----------------------- puertos y scanning -------------------------
 
+// ParsePorts interpreta una lista de puertos y rangos: "22,80,443" o "1-1024,8080".
+// Ignora entradas inválidas y puertos repetidos. Devuelve solo los puertos TCP
+// de una lista calificada por protocolo (ver ParsePortSpec).
 func ParsePorts(s string) []int {
-	out := []int{}
-	for _, p := range strings.Split(s, ",") {
-		p = strings.TrimSpace(p)
-		if p == "" {
-			continue
+	tcp, _ := ParsePortSpec(s)
+	return tcp
+}
+
+// ParsePortSpec separa puertos TCP y UDP: "udp:53,161 tcp:22,80". Cada prefijo
+// vale hasta el siguiente y lo que no tiene prefijo es TCP; las listas aceptan la
+// sintaxis de ParsePorts. Si no queda ningún puerto devuelve 22,80,443 TCP.
+func ParsePortSpec(s string) (tcp, udp []int) {
+	rest, proto := s, "tcp"
+	for rest != "" {
+		list := rest
+		loc := portProtoRe.FindStringSubmatchIndex(rest)
+		if loc != nil {
+			list = rest[:loc[0]]
 		}
-		if v, err := strconv.Atoi(p); err == nil && v > 0 && v <= 65535 {
-			out = append(out, v)
+		if proto == "udp" {
+			udp = parsePortList(list, udp)
+		} else {
+			tcp = parsePortList(list, tcp)
 		}
+		if loc == nil {
+			break
+		}
+		proto = strings.ToLower(rest[loc[2]:loc[3]])
+		rest = rest[loc[1]:]
 	}
-	if len(out) == 0 {
-		return []int{22, 80, 443}
+	if len(tcp) == 0 && len(udp) == 0 {
+		return []int{22, 80, 443}, nil
 	}
-	return out
+	return tcp, udp
 }
 
-// ////mejoramiento
-func simpleOUIVendor(mac string) string {
-	m := strings.ToLower(strings.ReplaceAll(mac, ":", ""))
-	if len(m) < 6 {
-		return ""
-	}
-	prefix := m[:6]
-	switch prefix {
-	case "00163e", "001e65", "f8f63d": // ejemplos Apple / Samsung / sospechosos (ajusta a tu OUI)
-		return "Apple"
-	case "34b3f6", "34a4f9":
-		return "Samsung"
-	case "3c5a37", "3c5a47":
-		return "Dahua/CameraVendor" // ejemplo
-	case "001d1a", "001ec0":
-		return "Hikvision/Camera"
-	case "a0b1c2":
-		return "Yealink/VoIP"
-	case "000b82":
-		return "Grandstream/VoIP"
+var portProtoRe = regexp.MustCompile(`(?i)\b(tcp|udp):`)
 
+// parsePortList agrega a out los puertos de "22,80 1000-1010" que no estén ya
+func parsePortList(s string, out []int) []int {
+	seen := map[int]bool{}
+	for _, v := range out {
+		seen[v] = true
 	}
-	return ""
-}
-
-// bannerProbe: intenta conectar TCP y leer primeros bytes (banner)
-func bannerProbe(ip string, port int, timeout time.Duration) string {
-	addr := fmt.Sprintf("%s:%d", ip, port)
-	conn, err := net.DialTimeout("tcp", addr, timeout)
-	if err != nil {
-		return ""
-	}
-	defer conn.Close()
-	conn.SetReadDeadline(time.Now().Add(timeout))
-	buf := make([]byte, 1024)
-	n, _ := conn.Read(buf)
-	return string(buf[:n])
-}
-
-// httpProbeTitle: hace GET / y devuelve header Server o <title> de la página
-func httpProbeTitle(ip string, port int, timeout time.Duration) (string, string) {
-	client := &http.Client{Timeout: timeout}
-	url := fmt.Sprintf("http://%s:%d/", ip, port)
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("User-Agent", "Mozilla/5.0 (scan)")
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", ""
+	add := func(v int) {
+		if v > 0 && v <= 65535 && !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
 	}
-	defer resp.Body.Close()
-
-	server := resp.Header.Get("Server")
-	// leer los primeros KB y buscar <title>
-	scanner := bufio.NewScanner(resp.Body)
-	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024)
-	title := ""
-	re := regexp.MustCompile(`(?i)<title[^>]*>([^<]+)</title>`)
-	count := 0
-	for scanner.Scan() && count < 20 {
-		line := scanner.Text()
-		if match := re.FindStringSubmatch(line); len(match) > 1 {
-			title = strings.TrimSpace(match[1])
-			break
+	for _, p := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		if lo, hi, ok := strings.Cut(p, "-"); ok {
+			a, errA := strconv.Atoi(lo)
+			b, errB := strconv.Atoi(hi)
+			if errA != nil || errB != nil {
+				continue
+			}
+			if a > b {
+				a, b = b, a
+			}
+			for v := max(a, 1); v <= min(b, 65535); v++ {
+				add(v)
+			}
+			continue
+		}
+		if v, err := strconv.Atoi(p); err == nil {
+			add(v)
 		}
-		count++
 	}
-	return server, title
+	return out
 }
 
-// enrichName intenta obtener mejor nombre/modelo: reverseDNS -> http Server/Title -> banner
-func enrichName(ip string, timeout time.Duration, currentName string) string {
+// enrichName intenta obtener mejor nombre/modelo: reverseDNS -> http title/realm/Server ->
+// fabricante y modelo RTSP -> banner
+func enrichName(s *hostSession, currentName string) string {
 	if currentName != "" {
 		return currentName
 	}
-	// 1) intentar http title/server
-	if tryTCP(ip, 80, timeout/2) {
-		server, title := httpProbeTitle(ip, 80, timeout/2)
-		if title != "" {
-			return title
+	// 1) intentar title, realm o Server de la primera interfaz web
+	for _, p := range defaultWebPorts {
+		info, ok := s.httpInfo(p)
+		if !ok {
+			continue
 		}
-		if server != "" {
-			return server
+		for _, name := range []string{info.Title, info.Realm, info.Server} {
+			if name != "" {
+				return name
+			}
 		}
 	}
-	// 2) banner probe common ports for model hints
-	ports := []int{554, 22, 80, 8080, 8000}
+	// 2) cámaras: fabricante y modelo según la respuesta RTSP (no envían banner)
+	for _, p := range rtspPorts {
+		if info, ok := s.rtspInfo(p); ok && info.Vendor != "" {
+			return strings.TrimSpace(info.Vendor + " " + info.Model)
+		}
+	}
+	// 3) banner probe common ports for model hints (el de SSH va en Result.SSH, no es un nombre)
+	ports := []int{80, 8080, 8000}
 	for _, p := range ports {
-		if tryTCP(ip, p, timeout/2) {
-			b := bannerProbe(ip, p, timeout/2)
+		if s.ctx.Err() != nil {
+			return ""
+		}
+		if s.isOpen(p) {
+			b := s.banner(p)
 			// buscar patrones comunes
 			bL := strings.ToLower(b)
 			if strings.Contains(bL, "hikvision") || strings.Contains(bL, "dahua") {
@@ -322,11 +579,11 @@ func enrichName(ip string, timeout time.Duration, currentName string) string {
 			}
 			if len(b) > 0 {
 				// recortar banner razonable
-				s := strings.TrimSpace(b)
-				if len(s) > 60 {
-					s = s[:60]
+				name := strings.TrimSpace(b)
+				if len(name) > 60 {
+					name = name[:60]
 				}
-				return s
+				return name
 			}
 		}
 	}
@@ -0,0 +1,39 @@
+package scan
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParsePortSpec(t *testing.T) {
+	cases := []struct {
+		spec     string
+		tcp, udp []int
+	}{
+		{"", []int{22, 80, 443}, nil},
+		{"22,80", []int{22, 80}, nil},
+		{"tcp:22,80 udp:53,123", []int{22, 80}, []int{53, 123}},
+		{"udp:53,161 tcp:22", []int{22}, []int{53, 161}},
+		{"UDP:53", nil, []int{53}},
+		{"22 udp:53 tcp:80", []int{22, 80}, []int{53}},
+		{"tcp:22\tudp:53\n", []int{22}, []int{53}},
+		{"udp:53 udp:53,161", nil, []int{53, 161}},
+		{"1-3", []int{1, 2, 3}, nil},
+		{"5-3", []int{3, 4, 5}, nil},
+		{"0-2", []int{1, 2}, nil},
+		{"65534-70000", []int{65534, 65535}, nil},
+		{"22,22,20-23", []int{22, 20, 21, 23}, nil},
+		{"abc,22x, 80", []int{80}, nil},
+		{"stcp:22,80", []int{80}, nil},
+		{"1-a,-5,80-", []int{22, 80, 443}, nil},
+		{"0,65536,-1", []int{22, 80, 443}, nil},
+		{"tcp:", []int{22, 80, 443}, nil},
+		{"udp:", []int{22, 80, 443}, nil},
+	}
+	for _, c := range cases {
+		tcp, udp := ParsePortSpec(c.spec)
+		if !slices.Equal(tcp, c.tcp) || !slices.Equal(udp, c.udp) {
+			t.Errorf("ParsePortSpec(%q) = %v %v, quiero %v %v", c.spec, tcp, udp, c.tcp, c.udp)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package scan
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"escaner/internal/models"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ----------------------- detección de servicios/versiones -------------------------
+
+// base de sondas por defecto, embebida en el binario
+//
+//go:embed data/service_probes.json
+var defaultServiceProbesJSON []byte
+
+// ServiceProbe una sonda: qué enviar, a qué puertos aplica y cómo reconocer la respuesta.
+// El payload y los patrones se tratan como latin-1: cada carácter \u0000-\u00ff es
+// un byte, así que "à" en el payload envía 0xE0 y `\xe0` en un patrón lo reconoce.
+// Un payload vacío solo espera el banner que envía el servidor al conectar.
+// Las sondas UDP envían el payload en un datagrama; una respuesta que matchea
+// alguno de sus patrones prueba que el host está vivo.
+type ServiceProbe struct {
+	Name     string         `json:"name"`
+	Protocol string         `json:"protocol,omitempty"` // "tcp" (por defecto) o "udp"
+	Payload  string         `json:"payload,omitempty"`
+	Ports    []int          `json:"ports"`
+	Fallback bool           `json:"fallback,omitempty"` // también se usa en puertos sin sonda específica
+	Matches  []ServiceMatch `json:"matches"`
+}
+
+// ServiceMatch regex sobre la respuesta; product/version/info/device_type
+// aceptan referencias a grupos ($1, ${2}).
+type ServiceMatch struct {
+	Service    string `json:"service"`
+	Pattern    string `json:"pattern"`
+	Product    string `json:"product,omitempty"`
+	Version    string `json:"version,omitempty"`
+	Info       string `json:"info,omitempty"`
+	DeviceType string `json:"device_type,omitempty"`
+
+	re *regexp.Regexp
+}
+
+type serviceProbeFile struct {
+	Probes []ServiceProbe `json:"probes"`
+}
+
+var (
+	serviceProbesMu sync.RWMutex
+	serviceProbes   []ServiceProbe
+)
+
+func init() {
+	probes, err := parseServiceProbes(defaultServiceProbesJSON)
+	if err != nil {
+		panic("service_probes.json embebido inválido: " + err.Error())
+	}
+	serviceProbes = probes
+}
+
+// LoadServiceProbes carga un archivo de sondas del usuario (mismo formato que el
+// embebido). Las sondas con el mismo nombre reemplazan a las por defecto y las
+// nuevas se agregan al final.
+func LoadServiceProbes(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error leyendo sondas de servicio: %w", err)
+	}
+	user, err := parseServiceProbes(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	serviceProbesMu.Lock()
+	defer serviceProbesMu.Unlock()
+	merged := slices.Clone(serviceProbes)
+	for _, p := range user {
+		i := slices.IndexFunc(merged, func(q ServiceProbe) bool { return q.Name == p.Name })
+		if i >= 0 {
+			merged[i] = p
+		} else {
+			merged = append(merged, p)
+		}
+	}
+	serviceProbes = merged
+	return nil
+}
+
+func parseServiceProbes(data []byte) ([]ServiceProbe, error) {
+	var f serviceProbeFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("json de sondas inválido: %w", err)
+	}
+	for i := range f.Probes {
+		p := &f.Probes[i]
+		if p.Name == "" {
+			return nil, fmt.Errorf("sonda #%d sin nombre", i+1)
+		}
+		if p.Protocol == "" {
+			p.Protocol = "tcp"
+		}
+		if p.Protocol != "tcp" && p.Protocol != "udp" {
+			return nil, fmt.Errorf("sonda %s: protocolo %q no soportado", p.Name, p.Protocol)
+		}
+		if p.Protocol == "udp" && p.Payload == "" {
+			return nil, fmt.Errorf("sonda %s: las sondas UDP necesitan payload", p.Name)
+		}
+		for j := range p.Matches {
+			m := &p.Matches[j]
+			re, err := regexp.Compile(m.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("sonda %s: patrón %q: %w", p.Name, m.Pattern, err)
+			}
+			m.re = re
+		}
+	}
+	return f.Probes, nil
+}
+
+// probesForPort sondas del protocolo que declaran el puerto. Si ninguna lo declara
+// se prueban los patrones de banner (solo TCP) y luego las sondas marcadas como fallback.
+func probesForPort(protocol string, port int) []ServiceProbe {
+	serviceProbesMu.RLock()
+	defer serviceProbesMu.RUnlock()
+	var specific, banner, fallback []ServiceProbe
+	for _, p := range serviceProbes {
+		if p.Protocol != protocol {
+			continue
+		}
+		switch {
+		case slices.Contains(p.Ports, port):
+			specific = append(specific, p)
+		case p.Payload == "":
+			banner = append(banner, p)
+		case p.Fallback:
+			fallback = append(fallback, p)
+		}
+	}
+	if len(specific) == 0 {
+		return append(banner, fallback...)
+	}
+	return specific
+}
+
+// detectServices identifica servicio y versión en cada puerto abierto (TCP y UDP)
+func detectServices(s *hostSession, tcpPorts, udpPorts []int) []models.Service {
+	var out []models.Service
+	for _, proto := range []struct {
+		name  string
+		ports []int
+	}{{"tcp", tcpPorts}, {"udp", udpPorts}} {
+		for _, port := range proto.ports {
+			if s.ctx.Err() != nil {
+				return out
+			}
+			if svc, ok := detectService(s, proto.name, port); ok {
+				out = append(out, svc)
+			}
+		}
+	}
+	return out
+}
+
+// detectService prueba las sondas del puerto en orden hasta que una reconoce la respuesta.
+// La sesión memoriza las respuestas, así que las sondas de solo banner comparten una lectura.
+func detectService(s *hostSession, protocol string, port int) (models.Service, bool) {
+	for _, p := range probesForPort(protocol, port) {
+		var resp string
+		if protocol == "udp" {
+			resp = s.udpExchange(port, p.Payload)
+		} else {
+			resp = s.exchange(port, p.Payload)
+		}
+		if resp == "" {
+			continue
+		}
+		for _, m := range p.Matches {
+			idx := m.re.FindStringSubmatchIndex(resp)
+			if idx == nil {
+				continue
+			}
+			expand := func(tmpl string) string {
+				if tmpl == "" {
+					return ""
+				}
+				v := m.re.ExpandString(nil, tmpl, resp, idx)
+				return strings.TrimSpace(string(v))
+			}
+			return models.Service{
+				Port:       port,
+				Protocol:   p.Protocol,
+				Name:       m.Service,
+				Product:    expand(m.Product),
+				Version:    expand(m.Version),
+				Info:       expand(m.Info),
+				DeviceType: expand(m.DeviceType),
+			}, true
+		}
+	}
+	return models.Service{}, false
+}
+
+// probeExchange conecta, envía payload (latin-1) y devuelve la respuesta como latin-1
+func probeExchange(ctx context.Context, ip string, port int, payload string, timeout time.Duration) string {
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	if payload != "" {
+		if _, err := conn.Write(latin1Bytes(payload)); err != nil {
+			return ""
+		}
+	}
+	buf := make([]byte, 4096)
+	n := 0
+	// acumular hasta llenar el buffer o que el servidor deje de enviar; tras el
+	// primer bloque solo esperamos un poco más (SSH, SMTP... no cierran la conexión)
+	for n < len(buf) {
+		m, err := conn.Read(buf[n:])
+		if m > 0 && n == 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(min(timeout, 150*time.Millisecond)))
+		}
+		n += m
+		if err != nil {
+			break
+		}
+	}
+	return latin1String(buf[:n])
+}
+
+// probeUDPExchange envía payload (latin-1) en un datagrama y devuelve la primera respuesta
+func probeUDPExchange(ctx context.Context, ip string, port int, payload string, timeout time.Duration) string {
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "udp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(latin1Bytes(payload)); err != nil {
+		return ""
+	}
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return ""
+	}
+	return latin1String(buf[:n])
+}
+
+// latin1Bytes convierte cada carácter (\u0000-\u00ff) en un byte
+func latin1Bytes(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		out = append(out, byte(r))
+	}
+	return out
+}
+
+// latin1String convierte cada byte en un carácter, para aplicar regex sobre binario
+func latin1String(b []byte) string {
+	rs := make([]rune, len(b))
+	for i, c := range b {
+		rs[i] = rune(c)
+	}
+	return string(rs)
+}
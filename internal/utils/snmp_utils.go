@@ -0,0 +1,763 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"escaner/internal/models"
+	"fmt"
+	"hash"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ----------------------- SNMP (v1 / v2c / v3) -------------------------
+
+// OIDs del grupo system (RFC 1213) que identifican al equipo
+const (
+	oidSysDescr    = "1.3.6.1.2.1.1.1.0"
+	oidSysObjectID = "1.3.6.1.2.1.1.2.0"
+	oidSysName     = "1.3.6.1.2.1.1.5.0"
+
+	// usmStatsNotInTimeWindows: el agente pide reintentar con su boots/time
+	oidUSMNotInTimeWindow = "1.3.6.1.6.3.15.1.1.2.0"
+)
+
+var snmpSystemOIDs = []string{oidSysDescr, oidSysObjectID, oidSysName}
+
+// SNMPCredentials comunidades (v2c y v1) y usuarios v3 con los que se consulta
+// a cada host. Se prueban todas las comunidades a la vez; los usuarios v3 solo
+// si ninguna comunidad respondió.
+type SNMPCredentials struct {
+	Communities []string     `json:"communities"`
+	V3          []SNMPv3User `json:"v3,omitempty"`
+}
+
+// SNMPv3User usuario USM. AuthProto: MD5, SHA, SHA224, SHA256, SHA384 o SHA512
+// (vacío = noAuthNoPriv). PrivProto: DES o AES (AES-128; vacío = sin cifrado).
+type SNMPv3User struct {
+	Username  string `json:"username"`
+	AuthProto string `json:"authProto,omitempty"`
+	AuthPass  string `json:"authPass,omitempty"`
+	PrivProto string `json:"privProto,omitempty"`
+	PrivPass  string `json:"privPass,omitempty"`
+}
+
+// DefaultSNMPCredentials solo la comunidad "public"
+func DefaultSNMPCredentials() *SNMPCredentials {
+	return &SNMPCredentials{Communities: []string{"public"}}
+}
+
+// Merge credenciales de extra sobre las de c: las de extra se prueban primero y
+// un usuario v3 con el mismo nombre reemplaza al de c. Cualquiera puede ser nil;
+// devuelve una copia nueva.
+func (c *SNMPCredentials) Merge(extra *SNMPCredentials) *SNMPCredentials {
+	out := &SNMPCredentials{}
+	seenComm := map[string]bool{}
+	seenUser := map[string]bool{}
+	for _, src := range []*SNMPCredentials{extra, c} {
+		if src == nil {
+			continue
+		}
+		for _, comm := range src.Communities {
+			if !seenComm[comm] {
+				seenComm[comm] = true
+				out.Communities = append(out.Communities, comm)
+			}
+		}
+		for _, u := range src.V3 {
+			if !seenUser[u.Username] {
+				seenUser[u.Username] = true
+				out.V3 = append(out.V3, u)
+			}
+		}
+	}
+	return out
+}
+
+// ParseSNMPv3User interpreta "usuario[:auth:clave[:priv:clave]]",
+// ej. "monitor:SHA:clave123:AES:otraclave"
+func ParseSNMPv3User(spec string) (SNMPv3User, error) {
+	parts := strings.Split(spec, ":")
+	u := SNMPv3User{Username: parts[0]}
+	switch len(parts) {
+	case 1:
+	case 3:
+		u.AuthProto, u.AuthPass = parts[1], parts[2]
+	case 5:
+		u.AuthProto, u.AuthPass = parts[1], parts[2]
+		u.PrivProto, u.PrivPass = parts[3], parts[4]
+	default:
+		return SNMPv3User{}, fmt.Errorf("usuario SNMPv3 %q: formato usuario[:auth:clave[:priv:clave]]", spec)
+	}
+	if u.Username == "" {
+		return SNMPv3User{}, fmt.Errorf("usuario SNMPv3 vacío")
+	}
+	if _, _, err := snmpAuthHash(u.AuthProto); err != nil {
+		return SNMPv3User{}, err
+	}
+	if u.PrivProto != "" {
+		if u.AuthProto == "" {
+			return SNMPv3User{}, fmt.Errorf("usuario SNMPv3 %s: el cifrado requiere autenticación", u.Username)
+		}
+		if p := strings.ToUpper(u.PrivProto); p != "DES" && p != "AES" {
+			return SNMPv3User{}, fmt.Errorf("protocolo de cifrado SNMPv3 %q no soportado (DES, AES)", u.PrivProto)
+		}
+	}
+	return u, nil
+}
+
+// snmpQuery pide sysDescr, sysObjectID y sysName por UDP 161
+func snmpQuery(ctx context.Context, ip string, timeout time.Duration, creds *SNMPCredentials, throttle *scanThrottle) (models.SNMPInfo, bool) {
+	if creds == nil {
+		return models.SNMPInfo{}, false
+	}
+	if info, ok := snmpCommunityGet(ctx, ip, timeout, creds.Communities, throttle); ok {
+		return info, true
+	}
+	for _, u := range creds.V3 {
+		if ctx.Err() != nil {
+			break
+		}
+		if info, err := snmpV3Get(ctx, ip, timeout, u, throttle); err == nil {
+			return info, true
+		}
+	}
+	return models.SNMPInfo{}, false
+}
+
+func snmpDial(ctx context.Context, ip string, timeout time.Duration) (net.Conn, func(), error) {
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "udp", net.JoinHostPort(ip, "161"))
+	if err != nil {
+		return nil, nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	return conn, func() { stop(); conn.Close() }, nil
+}
+
+// snmpCommunityGet envía juntas las consultas v2c y v1 de todas las comunidades
+// (un agente ignora en silencio las comunidades incorrectas) y se queda con la
+// primera respuesta válida: un host sin SNMP cuesta un solo timeout.
+func snmpCommunityGet(ctx context.Context, ip string, timeout time.Duration, communities []string, throttle *scanThrottle) (models.SNMPInfo, bool) {
+	if len(communities) == 0 {
+		return models.SNMPInfo{}, false
+	}
+	conn, closeConn, err := snmpDial(ctx, ip, timeout)
+	if err != nil {
+		return models.SNMPInfo{}, false
+	}
+	defer closeConn()
+
+	type pending struct{ version, community string }
+	sent := map[int32]pending{}
+	for _, c := range communities {
+		for _, v := range []struct {
+			num  int64
+			name string
+		}{{1, "v2c"}, {0, "v1"}} {
+			if throttle.wait(ctx, ip) != nil {
+				return models.SNMPInfo{}, false
+			}
+			id := rand.Int32N(1<<31 - 1)
+			pdu := snmpGetPDU(0xa0, id, snmpSystemOIDs)
+			msg := berTLV(0x30, berInt(0x02, v.num), berTLV(0x04, []byte(c)), pdu)
+			if _, err := conn.Write(msg); err == nil {
+				sent[id] = pending{v.name, c}
+			}
+		}
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return models.SNMPInfo{}, false
+		}
+		seq, err := berSeq(buf[:n], 0x30)
+		if err != nil || len(seq) < 3 {
+			continue
+		}
+		pdu, err := snmpParsePDU(seq[2])
+		if err != nil || pdu.tag != 0xa2 || pdu.errStatus != 0 {
+			continue
+		}
+		p, ok := sent[pdu.reqID]
+		if !ok {
+			continue
+		}
+		info := pdu.systemInfo()
+		info.Version = p.version
+		return info, true
+	}
+}
+
+// ---- SNMPv3 / USM (RFC 3414, 3826, 7860) ----
+
+// usmParams msgSecurityParameters
+type usmParams struct {
+	engineID    []byte
+	boots, time int64
+	user        []byte
+	auth, priv  []byte
+}
+
+func (p usmParams) encode() []byte {
+	return berTLV(0x30,
+		berTLV(0x04, p.engineID),
+		berInt(0x02, p.boots),
+		berInt(0x02, p.time),
+		berTLV(0x04, p.user),
+		berTLV(0x04, p.auth),
+		berTLV(0x04, p.priv),
+	)
+}
+
+// snmpV3Get descubre el engineID del agente y luego hace el GET autenticado
+// (y cifrado si el usuario tiene clave de privacidad). La respuesta se lee sin
+// verificar su HMAC: solo se usa para identificar el equipo.
+func snmpV3Get(ctx context.Context, ip string, timeout time.Duration, u SNMPv3User, throttle *scanThrottle) (models.SNMPInfo, error) {
+	conn, closeConn, err := snmpDial(ctx, ip, timeout)
+	if err != nil {
+		return models.SNMPInfo{}, err
+	}
+	defer closeConn()
+	exchange := func(msg []byte, msgID int32) (snmpV3Response, error) {
+		if err := throttle.wait(ctx, ip); err != nil {
+			return snmpV3Response{}, err
+		}
+		return snmpV3Exchange(conn, timeout, msg, msgID)
+	}
+
+	newHash, authLen, err := snmpAuthHash(u.AuthProto)
+	if err != nil {
+		return models.SNMPInfo{}, err
+	}
+
+	// 1) descubrimiento: GET vacío sin usuario; el agente responde un Report con
+	//    su engineID, boots y time
+	msgID := rand.Int32N(1<<31 - 1)
+	disc := snmpV3Message(msgID, 0x04, usmParams{}, snmpScopedPDU(nil, snmpGetPDU(0xa0, msgID, nil)))
+	resp, err := exchange(disc, msgID)
+	if err != nil {
+		return models.SNMPInfo{}, err
+	}
+	engine := resp.params
+	if len(engine.engineID) == 0 {
+		return models.SNMPInfo{}, fmt.Errorf("agente sin engineID")
+	}
+
+	var authKey, privKey []byte
+	flags := byte(0x04) // reportable
+	if newHash != nil {
+		flags |= 0x01
+		authKey = snmpLocalizedKey(newHash, u.AuthPass, engine.engineID)
+		if u.PrivProto != "" {
+			flags |= 0x02
+			privKey = snmpLocalizedKey(newHash, u.PrivPass, engine.engineID)
+		}
+	}
+
+	// 2) GET; si el agente contesta notInTimeWindow se reintenta una vez con su reloj
+	for attempt := 0; attempt < 2; attempt++ {
+		msgID = rand.Int32N(1<<31 - 1)
+		params := usmParams{
+			engineID: engine.engineID,
+			boots:    engine.boots,
+			time:     engine.time,
+			user:     []byte(u.Username),
+		}
+		scoped := snmpScopedPDU(engine.engineID, snmpGetPDU(0xa0, msgID, snmpSystemOIDs))
+		data := scoped
+		if privKey != nil {
+			enc, salt, err := snmpEncrypt(u.PrivProto, privKey, engine.boots, engine.time, scoped)
+			if err != nil {
+				return models.SNMPInfo{}, err
+			}
+			params.priv = salt
+			data = berTLV(0x04, enc)
+		}
+		if authKey != nil {
+			// HMAC sobre el mensaje con authParams en cero, luego el mismo mensaje con la firma
+			params.auth = make([]byte, authLen)
+			mac := hmac.New(newHash, authKey)
+			mac.Write(snmpV3Message(msgID, flags, params, data))
+			params.auth = mac.Sum(nil)[:authLen]
+		}
+		msg := snmpV3Message(msgID, flags, params, data)
+
+		resp, err := exchange(msg, msgID)
+		if err != nil {
+			return models.SNMPInfo{}, err
+		}
+		if resp.encrypted != nil {
+			if privKey == nil {
+				return models.SNMPInfo{}, fmt.Errorf("respuesta cifrada sin clave")
+			}
+			plain, err := snmpDecrypt(u.PrivProto, privKey, resp.params, resp.encrypted)
+			if err != nil {
+				return models.SNMPInfo{}, err
+			}
+			if resp.pdu, err = snmpParseScoped(plain); err != nil {
+				return models.SNMPInfo{}, err
+			}
+		}
+		pdu := resp.pdu
+		if pdu.tag == 0xa8 {
+			if len(pdu.vars) > 0 && pdu.vars[0].oid == oidUSMNotInTimeWindow && attempt == 0 {
+				engine.boots, engine.time = resp.params.boots, resp.params.time
+				continue
+			}
+			return models.SNMPInfo{}, fmt.Errorf("report del agente: %v", pdu.vars)
+		}
+		if pdu.tag != 0xa2 || pdu.errStatus != 0 {
+			return models.SNMPInfo{}, fmt.Errorf("respuesta SNMP con error %d", pdu.errStatus)
+		}
+		info := pdu.systemInfo()
+		info.Version = "v3"
+		return info, nil
+	}
+	return models.SNMPInfo{}, fmt.Errorf("fuera de la ventana de tiempo del agente")
+}
+
+// snmpV3Message mensaje completo: versión, datos globales, parámetros USM y datos
+func snmpV3Message(msgID int32, flags byte, params usmParams, data []byte) []byte {
+	global := berTLV(0x30,
+		berInt(0x02, int64(msgID)),
+		berInt(0x02, 65507),
+		berTLV(0x04, []byte{flags}),
+		berInt(0x02, 3), // modelo de seguridad USM
+	)
+	return berTLV(0x30, berInt(0x02, 3), global, berTLV(0x04, params.encode()), data)
+}
+
+func snmpScopedPDU(contextEngineID []byte, pdu []byte) []byte {
+	return berTLV(0x30, berTLV(0x04, contextEngineID), berTLV(0x04, nil), pdu)
+}
+
+type snmpV3Response struct {
+	params    usmParams
+	pdu       snmpPDU
+	encrypted []byte // ScopedPDU cifrado (si msgFlags trae priv)
+}
+
+// snmpV3Exchange envía msg y espera la respuesta con el mismo msgID
+func snmpV3Exchange(conn net.Conn, timeout time.Duration, msg []byte, msgID int32) (snmpV3Response, error) {
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(msg); err != nil {
+		return snmpV3Response{}, err
+	}
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return snmpV3Response{}, err
+		}
+		resp, id, err := snmpParseV3(buf[:n])
+		if err == nil && id == msgID {
+			return resp, nil
+		}
+	}
+}
+
+func snmpParseV3(b []byte) (snmpV3Response, int32, error) {
+	var resp snmpV3Response
+	msg, err := berSeq(b, 0x30)
+	if err != nil || len(msg) < 4 {
+		return resp, 0, errors.New("mensaje SNMPv3 inválido")
+	}
+	global, err := berSeq(msg[1].raw, 0x30)
+	if err != nil || len(global) < 1 {
+		return resp, 0, errors.New("msgGlobalData inválido")
+	}
+	msgID := int32(berIntValue(global[0].data))
+
+	spBytes := msg[2].data
+	sp, err := berSeq(spBytes, 0x30)
+	if err != nil || len(sp) < 6 {
+		return resp, 0, errors.New("msgSecurityParameters inválido")
+	}
+	resp.params = usmParams{
+		engineID: sp[0].data,
+		boots:    berIntValue(sp[1].data),
+		time:     berIntValue(sp[2].data),
+		user:     sp[3].data,
+		auth:     sp[4].data,
+		priv:     sp[5].data,
+	}
+	switch msg[3].tag {
+	case 0x04:
+		resp.encrypted = msg[3].data
+	case 0x30:
+		resp.pdu, err = snmpParseScoped(msg[3].raw)
+		if err != nil {
+			return resp, 0, err
+		}
+	default:
+		return resp, 0, errors.New("msgData inválido")
+	}
+	return resp, msgID, nil
+}
+
+func snmpParseScoped(b []byte) (snmpPDU, error) {
+	scoped, err := berSeq(b, 0x30)
+	if err != nil || len(scoped) < 3 {
+		return snmpPDU{}, errors.New("ScopedPDU inválido")
+	}
+	return snmpParsePDU(scoped[2])
+}
+
+// snmpAuthHash función de hash y largo de la firma (HMAC truncado) del protocolo
+func snmpAuthHash(proto string) (func() hash.Hash, int, error) {
+	switch strings.ToUpper(strings.ReplaceAll(proto, "-", "")) {
+	case "":
+		return nil, 0, nil
+	case "MD5":
+		return md5.New, 12, nil
+	case "SHA", "SHA1":
+		return sha1.New, 12, nil
+	case "SHA224":
+		return sha256.New224, 16, nil
+	case "SHA256":
+		return sha256.New, 24, nil
+	case "SHA384":
+		return sha512.New384, 32, nil
+	case "SHA512":
+		return sha512.New, 48, nil
+	}
+	return nil, 0, fmt.Errorf("protocolo de autenticación SNMPv3 %q no soportado", proto)
+}
+
+// snmpLocalizedKey clave del usuario localizada al engineID del agente (RFC 3414 A.2)
+func snmpLocalizedKey(newHash func() hash.Hash, password string, engineID []byte) []byte {
+	h := newHash()
+	if password != "" {
+		buf := make([]byte, 64)
+		for i, n := 0, 0; i < 1048576; i += len(buf) {
+			for j := range buf {
+				buf[j] = password[n%len(password)]
+				n++
+			}
+			h.Write(buf)
+		}
+	}
+	ku := h.Sum(nil)
+	h = newHash()
+	h.Write(ku)
+	h.Write(engineID)
+	h.Write(ku)
+	return h.Sum(nil)
+}
+
+// snmpEncrypt cifra el ScopedPDU: DES-CBC (RFC 3414 §8) o AES-128-CFB (RFC 3826).
+// Devuelve el texto cifrado y el salt que va en msgPrivacyParameters.
+func snmpEncrypt(proto string, key []byte, boots, engineTime int64, plain []byte) ([]byte, []byte, error) {
+	salt := make([]byte, 8)
+	switch strings.ToUpper(proto) {
+	case "DES":
+		if len(key) < 16 {
+			return nil, nil, errors.New("clave DES corta")
+		}
+		binary.BigEndian.PutUint32(salt, uint32(boots))
+		binary.BigEndian.PutUint32(salt[4:], rand.Uint32())
+		block, err := des.NewCipher(key[:8])
+		if err != nil {
+			return nil, nil, err
+		}
+		iv := make([]byte, 8)
+		for i := range iv {
+			iv[i] = key[8+i] ^ salt[i]
+		}
+		padded := append(bytes.Clone(plain), make([]byte, (8-len(plain)%8)%8)...)
+		out := make([]byte, len(padded))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, padded)
+		return out, salt, nil
+	case "AES":
+		binary.BigEndian.PutUint64(salt, rand.Uint64())
+		block, err := aes.NewCipher(key[:16])
+		if err != nil {
+			return nil, nil, err
+		}
+		out := make([]byte, len(plain))
+		cipher.NewCFBEncrypter(block, snmpAESIV(boots, engineTime, salt)).XORKeyStream(out, plain)
+		return out, salt, nil
+	}
+	return nil, nil, fmt.Errorf("protocolo de cifrado SNMPv3 %q no soportado", proto)
+}
+
+// snmpDecrypt descifra el ScopedPDU de una respuesta con el salt y reloj del agente
+func snmpDecrypt(proto string, key []byte, params usmParams, data []byte) ([]byte, error) {
+	if len(params.priv) != 8 {
+		return nil, errors.New("msgPrivacyParameters inválido")
+	}
+	switch strings.ToUpper(proto) {
+	case "DES":
+		if len(data)%8 != 0 || len(key) < 16 {
+			return nil, errors.New("datos DES inválidos")
+		}
+		block, err := des.NewCipher(key[:8])
+		if err != nil {
+			return nil, err
+		}
+		iv := make([]byte, 8)
+		for i := range iv {
+			iv[i] = key[8+i] ^ params.priv[i]
+		}
+		out := make([]byte, len(data))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+		return out, nil
+	case "AES":
+		block, err := aes.NewCipher(key[:16])
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(data))
+		cipher.NewCFBDecrypter(block, snmpAESIV(params.boots, params.time, params.priv)).XORKeyStream(out, data)
+		return out, nil
+	}
+	return nil, fmt.Errorf("protocolo de cifrado SNMPv3 %q no soportado", proto)
+}
+
+func snmpAESIV(boots, engineTime int64, salt []byte) []byte {
+	iv := make([]byte, 16)
+	binary.BigEndian.PutUint32(iv, uint32(boots))
+	binary.BigEndian.PutUint32(iv[4:], uint32(engineTime))
+	copy(iv[8:], salt)
+	return iv
+}
+
+// ---- PDU ----
+
+type snmpVar struct {
+	oid   string
+	value string
+}
+
+type snmpPDU struct {
+	tag       byte // 0xa2 GetResponse, 0xa8 Report
+	reqID     int32
+	errStatus int64
+	vars      []snmpVar
+}
+
+// systemInfo valores del grupo system de la respuesta
+func (p snmpPDU) systemInfo() models.SNMPInfo {
+	var info models.SNMPInfo
+	for _, v := range p.vars {
+		switch v.oid {
+		case oidSysDescr:
+			info.SysDescr = v.value
+		case oidSysObjectID:
+			info.SysObjectID = v.value
+		case oidSysName:
+			info.SysName = v.value
+		}
+	}
+	return info
+}
+
+// snmpGetPDU GetRequest (0xa0) con las OIDs pedidas y valores NULL
+func snmpGetPDU(tag byte, reqID int32, oids []string) []byte {
+	var vbs [][]byte
+	for _, o := range oids {
+		vbs = append(vbs, berTLV(0x30, berOID(o), []byte{0x05, 0x00}))
+	}
+	return berTLV(tag,
+		berInt(0x02, int64(reqID)),
+		berInt(0x02, 0),
+		berInt(0x02, 0),
+		berTLV(0x30, vbs...),
+	)
+}
+
+func snmpParsePDU(el berElement) (snmpPDU, error) {
+	fields, err := berSeq(el.raw, el.tag)
+	if err != nil || len(fields) < 4 {
+		return snmpPDU{}, errors.New("PDU inválido")
+	}
+	pdu := snmpPDU{
+		tag:       el.tag,
+		reqID:     int32(berIntValue(fields[0].data)),
+		errStatus: berIntValue(fields[1].data),
+	}
+	vbs, err := berSeq(fields[3].raw, 0x30)
+	if err != nil {
+		return snmpPDU{}, err
+	}
+	for _, vb := range vbs {
+		pair, err := berSeq(vb.raw, 0x30)
+		if err != nil || len(pair) < 2 || pair[0].tag != 0x06 {
+			continue
+		}
+		pdu.vars = append(pdu.vars, snmpVar{oid: berOIDValue(pair[0].data), value: snmpValueString(pair[1])})
+	}
+	return pdu, nil
+}
+
+// snmpValueString valor legible: texto, OID, número; binario como hex
+func snmpValueString(el berElement) string {
+	switch el.tag {
+	case 0x04: // OCTET STRING
+		s := strings.TrimRight(string(el.data), "\x00")
+		if utf8.ValidString(s) && !strings.ContainsFunc(s, func(r rune) bool { return r < 0x20 && r != '\n' && r != '\r' && r != '\t' }) {
+			return strings.TrimSpace(s)
+		}
+		return fmt.Sprintf("%x", el.data)
+	case 0x06:
+		return berOIDValue(el.data)
+	case 0x02, 0x41, 0x42, 0x43, 0x46: // INTEGER, Counter32, Gauge32, TimeTicks, Counter64
+		return fmt.Sprint(berIntValue(el.data))
+	case 0x40: // IpAddress
+		if len(el.data) == 4 {
+			return net.IP(el.data).String()
+		}
+	}
+	return ""
+}
+
+// ---- BER mínimo ----
+
+type berElement struct {
+	tag  byte
+	data []byte // contenido
+	raw  []byte // TLV completo
+}
+
+func berTLV(tag byte, parts ...[]byte) []byte {
+	n := 0
+	for _, p := range parts {
+		n += len(p)
+	}
+	out := []byte{tag}
+	switch {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xff:
+		out = append(out, 0x81, byte(n))
+	default:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	}
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func berInt(tag byte, v int64) []byte {
+	var b []byte
+	for {
+		b = append([]byte{byte(v)}, b...)
+		if v >= -128 && v < 128 {
+			break
+		}
+		v >>= 8
+	}
+	return berTLV(tag, b)
+}
+
+func berIntValue(b []byte) int64 {
+	var v int64
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		v = -1
+	}
+	for _, c := range b {
+		v = v<<8 | int64(c)
+	}
+	return v
+}
+
+func berOID(oid string) []byte {
+	var arcs []uint64
+	for _, p := range strings.Split(oid, ".") {
+		var a uint64
+		fmt.Sscan(p, &a)
+		arcs = append(arcs, a)
+	}
+	if len(arcs) < 2 {
+		return berTLV(0x06)
+	}
+	out := []byte{byte(arcs[0]*40 + arcs[1])}
+	for _, a := range arcs[2:] {
+		var enc []byte
+		enc = append(enc, byte(a&0x7f))
+		for a >>= 7; a > 0; a >>= 7 {
+			enc = append([]byte{byte(a&0x7f) | 0x80}, enc...)
+		}
+		out = append(out, enc...)
+	}
+	return berTLV(0x06, out)
+}
+
+func berOIDValue(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	arcs := []string{fmt.Sprint(b[0] / 40), fmt.Sprint(b[0] % 40)}
+	var a uint64
+	for _, c := range b[1:] {
+		a = a<<7 | uint64(c&0x7f)
+		if c&0x80 == 0 {
+			arcs = append(arcs, fmt.Sprint(a))
+			a = 0
+		}
+	}
+	return strings.Join(arcs, ".")
+}
+
+// berNext lee un TLV y devuelve el resto
+func berNext(b []byte) (berElement, []byte, error) {
+	if len(b) < 2 {
+		return berElement{}, nil, errors.New("ber truncado")
+	}
+	tag, l, off := b[0], int(b[1]), 2
+	if l&0x80 != 0 {
+		n := l & 0x7f
+		if n == 0 || n > 3 || len(b) < 2+n {
+			return berElement{}, nil, errors.New("largo ber inválido")
+		}
+		l = 0
+		for _, c := range b[2 : 2+n] {
+			l = l<<8 | int(c)
+		}
+		off += n
+	}
+	if len(b) < off+l {
+		return berElement{}, nil, errors.New("ber truncado")
+	}
+	return berElement{tag: tag, data: b[off : off+l], raw: b[:off+l]}, b[off+l:], nil
+}
+
+// berSeq lee un TLV con la etiqueta esperada y devuelve sus elementos
+func berSeq(b []byte, tag byte) ([]berElement, error) {
+	el, _, err := berNext(b)
+	if err != nil {
+		return nil, err
+	}
+	if el.tag != tag {
+		return nil, fmt.Errorf("etiqueta ber 0x%02x, esperada 0x%02x", el.tag, tag)
+	}
+	var out []berElement
+	rest := el.data
+	for len(rest) > 0 {
+		var child berElement
+		child, rest, err = berNext(rest)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, child)
+	}
+	return out, nil
+}
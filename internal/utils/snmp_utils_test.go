@@ -0,0 +1,209 @@
+package scan
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"hash"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestSNMPLocalizedKey vectores de RFC 3414 A.3.1 (MD5) y A.3.2 (SHA)
+func TestSNMPLocalizedKey(t *testing.T) {
+	engineID := mustHex(t, "000000000000000000000002")
+	for _, tc := range []struct {
+		name string
+		hash func() hash.Hash
+		kul  string
+	}{
+		{"MD5", md5.New, "526f5eed9fcce26f8964c2930787d82b"},
+		{"SHA", sha1.New, "6695febc9288e36282235fc7151f128497b38f3f"},
+	} {
+		got := snmpLocalizedKey(tc.hash, "maplesyrup", engineID)
+		if hex.EncodeToString(got) != tc.kul {
+			t.Errorf("%s: Kul = %x, quiero %s", tc.name, got, tc.kul)
+		}
+	}
+}
+
+func TestBERInt(t *testing.T) {
+	for _, tc := range []struct {
+		v   int64
+		enc string
+	}{
+		{0, "020100"},
+		{127, "02017f"},
+		{128, "02020080"},
+		{-1, "0201ff"},
+		{-128, "020180"},
+		{-129, "0202ff7f"},
+		{1<<31 - 1, "02047fffffff"},
+		{-1 << 31, "020480000000"},
+	} {
+		b := berInt(0x02, tc.v)
+		if hex.EncodeToString(b) != tc.enc {
+			t.Errorf("berInt(%d) = %x, quiero %s", tc.v, b, tc.enc)
+		}
+		el, rest, err := berNext(b)
+		if err != nil || len(rest) != 0 {
+			t.Fatalf("berNext(%x): %v, resto %x", b, err, rest)
+		}
+		if got := berIntValue(el.data); got != tc.v {
+			t.Errorf("berIntValue(%x) = %d, quiero %d", el.data, got, tc.v)
+		}
+	}
+}
+
+func TestBEROID(t *testing.T) {
+	for _, tc := range []struct {
+		oid string
+		enc string
+	}{
+		{"1.3.6.1.2.1.1.1.0", "06082b06010201010100"},
+		{"1.3.6.1.4.1.14988.1", "06082b06010401f50c01"},
+		{"1.3.6.1.4.1.9.1.4294967295", "060c2b0601040109018fffffff7f"},
+	} {
+		b := berOID(tc.oid)
+		if hex.EncodeToString(b) != tc.enc {
+			t.Errorf("berOID(%s) = %x, quiero %s", tc.oid, b, tc.enc)
+		}
+		el, _, err := berNext(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := berOIDValue(el.data); got != tc.oid {
+			t.Errorf("berOIDValue(%x) = %s, quiero %s", el.data, got, tc.oid)
+		}
+	}
+}
+
+// TestBERLongLength largos de una y dos bytes en forma larga (X.690 8.1.3.5)
+func TestBERLongLength(t *testing.T) {
+	for _, tc := range []struct {
+		n      int
+		header string
+	}{
+		{0x7f, "047f"},
+		{0x80, "048180"},
+		{0xff, "0481ff"},
+		{0x100, "04820100"},
+		{1500, "048205dc"},
+	} {
+		content := bytes.Repeat([]byte{'x'}, tc.n)
+		b := berTLV(0x04, content)
+		if h := hex.EncodeToString(b[:len(b)-tc.n]); h != tc.header {
+			t.Errorf("berTLV de %d bytes: cabecera %s, quiero %s", tc.n, h, tc.header)
+		}
+		el, rest, err := berNext(append(b, 0x05, 0x00))
+		if err != nil {
+			t.Fatalf("berNext de %d bytes: %v", tc.n, err)
+		}
+		if el.tag != 0x04 || !bytes.Equal(el.data, content) || !bytes.Equal(el.raw, b) || !bytes.Equal(rest, []byte{0x05, 0x00}) {
+			t.Errorf("berNext de %d bytes: tag %#x, %d bytes de datos, resto %x", tc.n, el.tag, len(el.data), rest)
+		}
+	}
+
+	if _, _, err := berNext(mustHex(t, "0482ffff00")); err == nil {
+		t.Error("berNext aceptó un largo mayor que el buffer")
+	}
+}
+
+// snmpV2cResponse GetResponse v2c de un MikroTik con sysDescr, sysObjectID,
+// sysUpTime y sysName, tal como llega por la red
+const snmpV2cResponse = "306c" +
+	"020101" + // versión v2c
+	"04067075626c6963" + // comunidad "public"
+	"a25f" +
+	"020412345678" + // request-id
+	"020100" + // error-status
+	"020100" + // error-index
+	"3051" +
+	"3018" + "06082b06010201010100" + "040c4c696e757820726f75746572" + // sysDescr "Linux router"
+	"3014" + "06082b06010201010200" + "06082b06010401f50c01" + // sysObjectID
+	"300f" + "06082b06010201010300" + "430301e240" + // sysUpTime 123456
+	"300e" + "06082b06010201010500" + "04026777" // sysName "gw"
+
+func TestSNMPParsePDU(t *testing.T) {
+	seq, err := berSeq(mustHex(t, snmpV2cResponse), 0x30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seq) != 3 || berIntValue(seq[0].data) != 1 || string(seq[1].data) != "public" {
+		t.Fatalf("cabecera del mensaje: %+v", seq)
+	}
+	pdu, err := snmpParsePDU(seq[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pdu.tag != 0xa2 || pdu.reqID != 0x12345678 || pdu.errStatus != 0 {
+		t.Errorf("tag %#x, reqID %#x, errStatus %d", pdu.tag, pdu.reqID, pdu.errStatus)
+	}
+	want := []snmpVar{
+		{oidSysDescr, "Linux router"},
+		{oidSysObjectID, "1.3.6.1.4.1.14988.1"},
+		{"1.3.6.1.2.1.1.3.0", "123456"},
+		{oidSysName, "gw"},
+	}
+	if len(pdu.vars) != len(want) {
+		t.Fatalf("vars = %+v", pdu.vars)
+	}
+	for i, v := range want {
+		if pdu.vars[i] != v {
+			t.Errorf("var %d = %+v, quiero %+v", i, pdu.vars[i], v)
+		}
+	}
+	info := pdu.systemInfo()
+	if info.SysDescr != "Linux router" || info.SysObjectID != "1.3.6.1.4.1.14988.1" || info.SysName != "gw" {
+		t.Errorf("systemInfo = %+v", info)
+	}
+}
+
+func TestSNMPCredentialsMerge(t *testing.T) {
+	profile := &SNMPCredentials{
+		Communities: []string{"public", "red"},
+		V3:          []SNMPv3User{{Username: "monitor", AuthProto: "MD5", AuthPass: "vieja"}},
+	}
+	req := &SNMPCredentials{
+		Communities: []string{"privada", "public"},
+		V3:          []SNMPv3User{{Username: "monitor", AuthProto: "SHA", AuthPass: "nueva"}, {Username: "lector"}},
+	}
+	cases := []struct {
+		name        string
+		base, extra *SNMPCredentials
+		want        *SNMPCredentials
+	}{
+		{"perfil sin SNMP", nil, req, &SNMPCredentials{
+			Communities: []string{"privada", "public"},
+			V3:          []SNMPv3User{{Username: "monitor", AuthProto: "SHA", AuthPass: "nueva"}, {Username: "lector"}},
+		}},
+		{"pedido sin SNMP", profile, nil, &SNMPCredentials{
+			Communities: []string{"public", "red"},
+			V3:          []SNMPv3User{{Username: "monitor", AuthProto: "MD5", AuthPass: "vieja"}},
+		}},
+		{"ambos", profile, req, &SNMPCredentials{
+			Communities: []string{"privada", "public", "red"},
+			V3:          []SNMPv3User{{Username: "monitor", AuthProto: "SHA", AuthPass: "nueva"}, {Username: "lector"}},
+		}},
+		{"ninguno", nil, nil, &SNMPCredentials{}},
+	}
+	for _, c := range cases {
+		if got := c.base.Merge(c.extra); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: Merge = %+v, quiero %+v", c.name, got, c.want)
+		}
+	}
+	if len(profile.Communities) != 2 || profile.V3[0].AuthPass != "vieja" {
+		t.Errorf("Merge modificó el perfil: %+v", profile)
+	}
+}
@@ -0,0 +1,211 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"escaner/internal/models"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/ipv4"
+)
+
+// ----------------------- descubrimiento UPnP / SSDP -------------------------
+
+var ssdpGroup4 = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+
+// tamaño máximo de una descripción de dispositivo que se acepta
+const upnpMaxDescription = 256 << 10
+
+// upnpClient no sigue redirecciones: la descripción debe venir del mismo equipo
+var upnpClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// ssdpDiscovery fase de descubrimiento que corre en paralelo al escaneo, igual
+// que mdnsDiscovery; lookup espera a que termine la ventana y las descargas
+type ssdpDiscovery struct {
+	done chan struct{}
+
+	mu    sync.Mutex
+	hosts map[string]*models.UPnPInfo // ip -> descripción del dispositivo raíz
+}
+
+// startSSDPDiscovery envía un M-SEARCH ssdp:all por cada interfaz IPv4, escucha
+// respuestas durante window y descarga la descripción XML (LOCATION) de cada
+// equipo que respondió.
+func startSSDPDiscovery(ctx context.Context, window, timeout time.Duration, throttle *scanThrottle) *ssdpDiscovery {
+	d := &ssdpDiscovery{done: make(chan struct{}), hosts: map[string]*models.UPnPInfo{}}
+	go func() {
+		defer close(d.done)
+		d.run(ctx, window, timeout, throttle)
+	}()
+	return d
+}
+
+// lookup devuelve la descripción UPnP de ip (espera a que termine el descubrimiento)
+func (d *ssdpDiscovery) lookup(ctx context.Context, ip string) (models.UPnPInfo, bool) {
+	if d == nil {
+		return models.UPnPInfo{}, false
+	}
+	select {
+	case <-d.done:
+	case <-ctx.Done():
+		return models.UPnPInfo{}, false
+	}
+	info, ok := d.hosts[stripZone(ip)]
+	if !ok {
+		return models.UPnPInfo{}, false
+	}
+	return *info, true
+}
+
+func (d *ssdpDiscovery) run(ctx context.Context, window, timeout time.Duration, throttle *scanThrottle) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	query := ssdpMSearch(max(int(window/time.Second), 1))
+	pc := ipv4.NewPacketConn(conn)
+	_ = pc.SetMulticastTTL(2)
+	ifaces, _ := net.Interfaces()
+	sent := false
+	for i := range ifaces {
+		iface := &ifaces[i]
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		if throttle.wait(ctx, ssdpGroup4.IP.String()) != nil {
+			return
+		}
+		if pc.SetMulticastInterface(iface) != nil {
+			continue
+		}
+		if _, err := pc.WriteTo(query, nil, ssdpGroup4); err == nil {
+			sent = true
+		}
+	}
+	if !sent {
+		return
+	}
+
+	// cada equipo responde una vez por servicio: descargar cada LOCATION una sola vez
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	fetched := map[string]bool{}
+	_ = conn.SetReadDeadline(time.Now().Add(window))
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		src, ok := netip.AddrFromSlice(from.IP)
+		if !ok {
+			continue
+		}
+		ip := src.Unmap().String()
+		loc := ssdpLocation(buf[:n], ip)
+		if loc == "" || fetched[loc] {
+			continue
+		}
+		fetched[loc] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if throttle.wait(ctx, ip) != nil {
+				return
+			}
+			if info, ok := fetchUPnPDescription(ctx, loc, timeout); ok {
+				d.mu.Lock()
+				if _, dup := d.hosts[ip]; !dup {
+					d.hosts[ip] = &info
+				}
+				d.mu.Unlock()
+			}
+		}()
+	}
+}
+
+func ssdpMSearch(mx int) []byte {
+	return []byte("M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: 239.255.255.250:1900\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: " + strconv.Itoa(mx) + "\r\n" +
+		"ST: ssdp:all\r\n\r\n")
+}
+
+// ssdpLocation URL de la descripción en una respuesta al M-SEARCH. Solo se acepta
+// si apunta al mismo equipo que respondió: así los datos quedan asociados a esa
+// IP y no se hacen pedidos a terceros.
+func ssdpLocation(msg []byte, ip string) string {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(msg)), nil)
+	if err != nil {
+		return ""
+	}
+	resp.Body.Close()
+	u, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || u.Scheme != "http" || u.Hostname() != ip {
+		return ""
+	}
+	return u.String()
+}
+
+// upnpDescription lo que interesa del XML de descripción (UPnP Device Architecture §2.3)
+type upnpDescription struct {
+	Device struct {
+		DeviceType   string `xml:"deviceType"`
+		FriendlyName string `xml:"friendlyName"`
+		Manufacturer string `xml:"manufacturer"`
+		ModelName    string `xml:"modelName"`
+		ModelNumber  string `xml:"modelNumber"`
+		SerialNumber string `xml:"serialNumber"`
+	} `xml:"device"`
+}
+
+func fetchUPnPDescription(ctx context.Context, location string, timeout time.Duration) (models.UPnPInfo, bool) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return models.UPnPInfo{}, false
+	}
+	resp, err := upnpClient.Do(req)
+	if err != nil {
+		return models.UPnPInfo{}, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return models.UPnPInfo{}, false
+	}
+	var desc upnpDescription
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, upnpMaxDescription)).Decode(&desc); err != nil {
+		return models.UPnPInfo{}, false
+	}
+	dev := desc.Device
+	info := models.UPnPInfo{
+		FriendlyName: strings.TrimSpace(dev.FriendlyName),
+		Manufacturer: strings.TrimSpace(dev.Manufacturer),
+		ModelName:    strings.TrimSpace(dev.ModelName),
+		ModelNumber:  strings.TrimSpace(dev.ModelNumber),
+		SerialNumber: strings.TrimSpace(dev.SerialNumber),
+		DeviceType:   strings.TrimSpace(dev.DeviceType),
+	}
+	if info == (models.UPnPInfo{}) {
+		return models.UPnPInfo{}, false
+	}
+	return info, true
+}
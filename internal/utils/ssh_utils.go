@@ -0,0 +1,130 @@
+package scan
+
+import (
+	_ "embed"
+	"encoding/json"
+	"escaner/internal/models"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ----------------------- identificación SSH -------------------------
+
+// tabla de banners por defecto, embebida en el binario
+//
+//go:embed data/ssh_banners.json
+var defaultSSHBannersJSON []byte
+
+// puertos donde se busca un servidor SSH
+var sshPorts = []int{22, 2222}
+
+// sshBannerRule deduce sistema, distribución y familia de equipo a partir de la
+// identificación SSH. Pattern se aplica a lo que sigue a "SSH-2.0-" (software y
+// comentarios: "OpenSSH_8.9p1 Ubuntu-3ubuntu0.6"); gana la primera que matchea y
+// sus campos aceptan referencias a grupos ($1, ${2}).
+type sshBannerRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	OS      string `json:"os,omitempty"`
+	Distro  string `json:"distro,omitempty"`
+	Release string `json:"release,omitempty"`
+	Family  string `json:"family,omitempty"` // router, network o embedded; vacío = sistema de uso general
+
+	re *regexp.Regexp
+}
+
+var sshBannerRules []sshBannerRule
+
+func init() {
+	var f struct {
+		Banners []sshBannerRule `json:"banners"`
+	}
+	if err := json.Unmarshal(defaultSSHBannersJSON, &f); err != nil {
+		panic("ssh_banners.json embebido inválido: " + err.Error())
+	}
+	for i := range f.Banners {
+		r := &f.Banners[i]
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			panic(fmt.Sprintf("ssh_banners.json embebido: %s: %v", r.Name, err))
+		}
+		r.re = re
+	}
+	sshBannerRules = f.Banners
+}
+
+// inspectSSH identificación de los puertos SSH conocidos que están en ports y de
+// los que las sondas de servicio identificaron como SSH
+func inspectSSH(s *hostSession, ports []int, services []models.Service) []models.SSHInfo {
+	var candidates []int
+	for _, p := range sshPorts {
+		if slices.Contains(ports, p) {
+			candidates = append(candidates, p)
+		}
+	}
+	for _, svc := range services {
+		if svc.Protocol == "tcp" && svc.Name == "ssh" && !slices.Contains(candidates, svc.Port) {
+			candidates = append(candidates, svc.Port)
+		}
+	}
+	var out []models.SSHInfo
+	for _, p := range candidates {
+		if info, ok := s.sshInfo(p); ok {
+			out = append(out, info)
+		}
+	}
+	return out
+}
+
+// parseSSHBanner separa la línea de identificación (RFC 4253 §4.2,
+// "SSH-protoversion-softwareversion comentarios") y la cruza con la tabla de banners
+func parseSSHBanner(port int, banner string) (models.SSHInfo, bool) {
+	var ident string
+	// el servidor puede mandar otras líneas antes de la identificación
+	for _, line := range strings.Split(banner, "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.HasPrefix(line, "SSH-") {
+			ident = line
+			break
+		}
+	}
+	proto, rest, ok := strings.Cut(strings.TrimPrefix(ident, "SSH-"), "-")
+	if !ok || rest == "" {
+		return models.SSHInfo{}, false
+	}
+	software, comment, _ := strings.Cut(rest, " ")
+	info := models.SSHInfo{
+		Port:     port,
+		Banner:   ident,
+		Protocol: proto,
+		Product:  software,
+		Comment:  strings.TrimSpace(comment),
+	}
+	// "OpenSSH_8.9p1", "dropbear_2022.83", "Cisco-1.25", "OpenSSH_for_Windows_8.1"
+	if i := strings.LastIndexAny(software, "_-"); i > 0 && i+1 < len(software) && software[i+1] >= '0' && software[i+1] <= '9' {
+		info.Product = strings.ReplaceAll(software[:i], "_", " ")
+		info.Version = software[i+1:]
+	}
+	for _, r := range sshBannerRules {
+		idx := r.re.FindStringSubmatchIndex(rest)
+		if idx == nil {
+			continue
+		}
+		expand := func(tmpl string) string {
+			return strings.TrimSpace(string(r.re.ExpandString(nil, tmpl, rest, idx)))
+		}
+		info.OS = expand(r.OS)
+		info.Distro = expand(r.Distro)
+		info.Release = expand(r.Release)
+		info.Family = expand(r.Family)
+		break
+	}
+	return info, true
+}
+
+// sshText "producto versión sistema distribución release familia": el texto que
+// miran las reglas ssh
+func sshText(info models.SSHInfo) string {
+	return strings.Join(strings.Fields(strings.Join([]string{info.Product, info.Version, info.OS, info.Distro, info.Release, info.Family}, " ")), " ")
+}
@@ -0,0 +1,233 @@
+package scan
+
+import (
+	"fmt"
+	"iter"
+	"math/big"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxIPv6Hosts límite de direcciones al expandir prefijos/rangos IPv6:
+// un /64 no se puede barrer, así que solo aceptamos bloques acotados (/112 o menores).
+const maxIPv6Hosts = 1 << 16
+
+// Targets conjunto de objetivos (CIDRs, rangos e IPs sueltas, IPv4 o IPv6) que se
+// recorre de forma perezosa: nunca se materializan todas las direcciones, así que
+// un /16 o un /8 ocupan lo mismo en memoria que una IP.
+type Targets struct {
+	include []addrRange
+	exclude []addrRange
+
+	// KeepNetBcast incluye la dirección de red y broadcast de los prefijos IPv4
+	// (por defecto se omiten, salvo en /31 y /32)
+	KeepNetBcast bool
+}
+
+// addrRange rango inclusivo lo-hi de una misma familia
+type addrRange struct {
+	lo, hi netip.Addr
+	prefix bool // viene de un CIDR IPv4: se omiten red y broadcast
+}
+
+// ParseTargets arma un Targets a partir de argumentos de objetivos y exclusiones.
+// Cada argumento puede tener varios objetivos separados por comas.
+func ParseTargets(args []string, excludes []string) (*Targets, error) {
+	t := &Targets{}
+	for _, a := range args {
+		if err := t.Add(a); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range excludes {
+		if err := t.Exclude(e); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Add agrega objetivos: 192.168.1.0/24, 192.168.1.10-50, 10.0.0.1-10.0.0.9,
+// 2001:db8::/120, fe80::1%eth0 (varios separados por comas)
+func (t *Targets) Add(arg string) error {
+	for _, part := range splitTargets(arg) {
+		r, err := parseTargetRange(part)
+		if err != nil {
+			return fmt.Errorf("%s: %w", part, err)
+		}
+		t.include = append(t.include, r)
+	}
+	return nil
+}
+
+// Exclude quita del recorrido las direcciones del argumento (mismo formato que Add)
+func (t *Targets) Exclude(arg string) error {
+	for _, part := range splitTargets(arg) {
+		r, err := parseTargetRange(part)
+		if err != nil {
+			return fmt.Errorf("exclusión %s: %w", part, err)
+		}
+		r.prefix = false
+		t.exclude = append(t.exclude, r)
+	}
+	return nil
+}
+
+// Empty indica si no se agregó ningún objetivo
+func (t *Targets) Empty() bool {
+	return len(t.include) == 0
+}
+
+// All recorre las direcciones en orden (IPv4 antes que IPv6), sin duplicados y
+// sin las exclusiones. El recorrido se detiene si el consumidor corta el range.
+func (t *Targets) All() iter.Seq[string] {
+	include := make([]addrRange, 0, len(t.include))
+	for _, r := range t.include {
+		// quitar red y broadcast antes de fusionar para no perderlas entre bloques contiguos
+		if r.prefix && !t.KeepNetBcast && r.lo != r.hi && r.lo.Next() != r.hi {
+			r.lo, r.hi = r.lo.Next(), r.hi.Prev()
+		}
+		include = append(include, r)
+	}
+	include = mergeRanges(include)
+	exclude := mergeRanges(t.exclude)
+	return func(yield func(string) bool) {
+		for _, r := range include {
+			hi := r.hi
+			for a := r.lo; a.IsValid() && a.Compare(hi) <= 0; a = a.Next() {
+				// saltar de una vez todo el rango excluido que contiene a
+				if skip, ok := excludedUntil(exclude, a); ok {
+					if !skip.IsValid() || skip.Compare(hi) >= 0 {
+						break
+					}
+					a = skip
+					continue
+				}
+				if !yield(a.String()) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// excludedUntil si a está excluida devuelve el último elemento del rango que la excluye
+func excludedUntil(exclude []addrRange, a netip.Addr) (netip.Addr, bool) {
+	for _, e := range exclude {
+		if a.Compare(e.lo) >= 0 && a.Compare(e.hi) <= 0 {
+			return e.hi, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// mergeRanges ordena y fusiona rangos solapados o contiguos (para no repetir IPs)
+func mergeRanges(in []addrRange) []addrRange {
+	rs := slices.Clone(in)
+	slices.SortFunc(rs, func(a, b addrRange) int { return a.lo.Compare(b.lo) })
+	var out []addrRange
+	for _, r := range rs {
+		if n := len(out); n > 0 {
+			last := &out[n-1]
+			if last.lo.Is4() == r.lo.Is4() && last.lo.Zone() == r.lo.Zone() &&
+				(r.lo.Compare(last.hi) <= 0 || r.lo == last.hi.Next()) {
+				if r.hi.Compare(last.hi) > 0 {
+					last.hi = r.hi
+				}
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func splitTargets(arg string) []string {
+	var out []string
+	for _, p := range strings.Split(arg, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// parseTargetRange interpreta un objetivo como rango inclusivo
+func parseTargetRange(arg string) (addrRange, error) {
+	// CIDR (IPv4 o IPv6)
+	if strings.Contains(arg, "/") {
+		prefix, err := netip.ParsePrefix(arg)
+		if err != nil {
+			return addrRange{}, err
+		}
+		prefix = prefix.Masked()
+		lo := prefix.Addr()
+		hostBits := lo.BitLen() - prefix.Bits()
+		if lo.Is6() && hostBits > 16 {
+			return addrRange{}, fmt.Errorf("prefijo IPv6 /%d demasiado grande: máximo %d direcciones (/112)", prefix.Bits(), maxIPv6Hosts)
+		}
+		return addrRange{lo: lo, hi: lastAddr(prefix), prefix: lo.Is4()}, nil
+	}
+	if strings.Count(arg, "-") == 1 {
+		parts := strings.Split(arg, "-")
+		left := strings.TrimSpace(parts[0])
+		right := strings.TrimSpace(parts[1])
+		a, err := netip.ParseAddr(left)
+		if err != nil {
+			return addrRange{}, fmt.Errorf("rango ip inválido")
+		}
+		a = a.Unmap()
+		// shorthand last-octet e.g., 192.168.181.1-255
+		if hi, err := strconv.Atoi(right); err == nil && a.Is4() {
+			if hi < 0 || hi > 255 {
+				return addrRange{}, fmt.Errorf("rango ip inválido")
+			}
+			b4 := a.As4()
+			b4[3] = byte(hi)
+			return orderedRange(a, netip.AddrFrom4(b4))
+		}
+		// full range: 192.168.1.10-192.168.1.50 o 2001:db8::10-2001:db8::50
+		b, err := netip.ParseAddr(right)
+		if err != nil {
+			return addrRange{}, fmt.Errorf("rango ip inválido")
+		}
+		return orderedRange(a, b.Unmap())
+	}
+	// single IP (acepta zona IPv6, ej. fe80::1%eth0)
+	if addr, err := netip.ParseAddr(arg); err == nil {
+		addr = addr.Unmap()
+		return addrRange{lo: addr, hi: addr}, nil
+	}
+	return addrRange{}, fmt.Errorf("formato de IP no reconocido")
+}
+
+func orderedRange(a, b netip.Addr) (addrRange, error) {
+	if a.Is4() != b.Is4() {
+		return addrRange{}, fmt.Errorf("el rango mezcla IPv4 e IPv6")
+	}
+	if b.Less(a) {
+		a, b = b, a
+	}
+	if a.Is6() {
+		size := new(big.Int).Sub(new(big.Int).SetBytes(b.AsSlice()), new(big.Int).SetBytes(a.AsSlice()))
+		if size.Cmp(big.NewInt(maxIPv6Hosts)) >= 0 {
+			return addrRange{}, fmt.Errorf("rango IPv6 demasiado grande: máximo %d direcciones", maxIPv6Hosts)
+		}
+	}
+	return addrRange{lo: a, hi: b}, nil
+}
+
+// lastAddr última dirección de un prefijo (broadcast en IPv4)
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Addr().AsSlice()
+	hostBits := len(b)*8 - p.Bits()
+	for i := len(b) - 1; i >= 0 && hostBits > 0; i-- {
+		n := min(hostBits, 8)
+		b[i] |= byte(1<<n - 1)
+		hostBits -= n
+	}
+	out, _ := netip.AddrFromSlice(b)
+	return out
+}
@@ -0,0 +1,96 @@
+package scan
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTargetsAll(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		excludes []string
+		keep     bool // KeepNetBcast
+		want     []string
+	}{
+		{"CIDR sin red ni broadcast", []string{"192.168.1.0/30"}, nil, false, []string{"192.168.1.1", "192.168.1.2"}},
+		{"CIDR con red y broadcast", []string{"192.168.1.0/30"}, nil, true, []string{"192.168.1.0", "192.168.1.1", "192.168.1.2", "192.168.1.3"}},
+		{"/31 completo", []string{"10.0.0.0/31"}, nil, false, []string{"10.0.0.0", "10.0.0.1"}},
+		{"/32", []string{"10.0.0.7/32"}, nil, false, []string{"10.0.0.7"}},
+		{"CIDR no alineado", []string{"10.0.0.5/30"}, nil, false, []string{"10.0.0.5", "10.0.0.6"}},
+		{"rango corto", []string{"10.0.0.5-7"}, nil, false, []string{"10.0.0.5", "10.0.0.6", "10.0.0.7"}},
+		{"rango corto invertido", []string{"10.0.0.7-5"}, nil, false, []string{"10.0.0.5", "10.0.0.6", "10.0.0.7"}},
+		{"rango completo entre /24", []string{"10.0.0.254-10.0.1.1"}, nil, false, []string{"10.0.0.254", "10.0.0.255", "10.0.1.0", "10.0.1.1"}},
+		{"solapados sin repetir", []string{"10.0.0.1-3,10.0.0.2-4", "10.0.0.3"}, nil, false, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}},
+		{"CIDRs contiguos", []string{"10.0.0.0/30,10.0.0.4/30"}, nil, false, []string{"10.0.0.1", "10.0.0.2", "10.0.0.5", "10.0.0.6"}},
+		{"exclusión en el medio", []string{"10.0.0.0/29"}, []string{"10.0.0.2-4"}, false, []string{"10.0.0.1", "10.0.0.5", "10.0.0.6"}},
+		{"exclusión hasta el final", []string{"10.0.0.1-5"}, []string{"10.0.0.4-10.0.0.200"}, false, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		{"exclusión de todo", []string{"10.0.0.1"}, []string{"10.0.0.0/24"}, false, nil},
+		{"exclusión de otro rango", []string{"10.0.0.1"}, []string{"10.0.1.0/24,2001:db8::1"}, false, []string{"10.0.0.1"}},
+		{"IPv4 antes que IPv6", []string{"2001:db8::1", "10.0.0.1"}, nil, false, []string{"10.0.0.1", "2001:db8::1"}},
+		{"prefijo IPv6 completo", []string{"2001:db8::/126"}, nil, false, []string{"2001:db8::", "2001:db8::1", "2001:db8::2", "2001:db8::3"}},
+		{"rango IPv6", []string{"2001:db8::fe-2001:db8::101"}, nil, false, []string{"2001:db8::fe", "2001:db8::ff", "2001:db8::100", "2001:db8::101"}},
+		{"zona IPv6", []string{"fe80::1%eth0"}, nil, false, []string{"fe80::1%eth0"}},
+		{"IPv4 mapeada", []string{"::ffff:10.0.0.1"}, nil, false, []string{"10.0.0.1"}},
+		{"fin del espacio IPv4", []string{"255.255.255.254-255"}, nil, false, []string{"255.255.255.254", "255.255.255.255"}},
+		{"espacios y comas vacías", []string{" 10.0.0.1 , ,10.0.0.2"}, nil, false, []string{"10.0.0.1", "10.0.0.2"}},
+	}
+	for _, c := range cases {
+		targets, err := ParseTargets(c.args, c.excludes)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		targets.KeepNetBcast = c.keep
+		if got := slices.Collect(targets.All()); !slices.Equal(got, c.want) {
+			t.Errorf("%s: All = %v, quiero %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestParseTargetsErrors(t *testing.T) {
+	for _, arg := range []string{
+		"",
+		"10.0.0",
+		"10.0.0.1-300",
+		"10.0.0.1-10.0.0",
+		"10.0.0.1-::1",
+		"10.0.0.0/33",
+		"2001:db8::/64",
+		"2001:db8::-2001:db8::1:0",
+		"10.0.0.1-2-3",
+		"impresora.local",
+	} {
+		targets, err := ParseTargets([]string{arg}, nil)
+		if arg == "" {
+			if err != nil || !targets.Empty() {
+				t.Errorf("%q: quiero objetivos vacíos sin error, hay %v", arg, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%q: quiero error", arg)
+		}
+	}
+	if _, err := ParseTargets([]string{"10.0.0.0/24"}, []string{"10.0.0.300"}); err == nil {
+		t.Error("exclusión inválida: quiero error")
+	}
+}
+
+// TestTargetsAllStops el recorrido de un /8 termina apenas el consumidor corta
+func TestTargetsAllStops(t *testing.T) {
+	targets, err := ParseTargets([]string{"10.0.0.0/8"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for ip := range targets.All() {
+		got = append(got, ip)
+		if len(got) == 3 {
+			break
+		}
+	}
+	if want := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}; !slices.Equal(got, want) {
+		t.Errorf("All = %v, quiero %v", got, want)
+	}
+}
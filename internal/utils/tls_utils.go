@@ -0,0 +1,134 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"escaner/internal/models"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ----------------------- inspección de certificados TLS -------------------------
+
+// puertos donde se espera TLS desde el primer byte (HTTPS, paneles de NAS/BMC,
+// LDAPS, IMAPS, POP3S, SMTPS)
+var tlsPorts = []int{443, 8443, 4443, 9443, 5001, 636, 993, 995, 465}
+
+// certExpiryWarning con menos de este margen el certificado se marca ExpiresSoon
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// tlsProbeConfig acepta cualquier certificado (se inspecciona, no se valida) y
+// versiones y cifrados viejos: muchas impresoras, iLO e iDRAC solo hablan TLS 1.0
+// con intercambio RSA
+var tlsProbeConfig = &tls.Config{
+	InsecureSkipVerify: true,
+	MinVersion:         tls.VersionTLS10,
+	CipherSuites:       allCipherSuites(),
+}
+
+func allCipherSuites() []uint16 {
+	var ids []uint16
+	for _, cs := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		ids = append(ids, cs.ID)
+	}
+	return ids
+}
+
+// inspectTLS certificados de los puertos TLS conocidos que están en ports y abiertos
+func inspectTLS(s *hostSession, ports []int) []models.TLSCert {
+	var out []models.TLSCert
+	for _, p := range tlsPorts {
+		if !slices.Contains(ports, p) || !s.isOpen(p) {
+			continue
+		}
+		if cert, ok := s.tlsCert(p); ok {
+			out = append(out, cert)
+		}
+	}
+	return out
+}
+
+// probeTLSCert hace el handshake y describe el certificado del servidor
+func probeTLSCert(ctx context.Context, ip string, port int, timeout time.Duration) (models.TLSCert, bool) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	d := tls.Dialer{NetDialer: &net.Dialer{}, Config: tlsProbeConfig}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil {
+		return models.TLSCert{}, false
+	}
+	defer conn.Close()
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return models.TLSCert{}, false
+	}
+	return describeCert(certs[0], port, time.Now()), true
+}
+
+func describeCert(c *x509.Certificate, port int, now time.Time) models.TLSCert {
+	cert := models.TLSCert{
+		Port:        port,
+		Subject:     c.Subject.CommonName,
+		Issuer:      c.Issuer.String(),
+		NotBefore:   c.NotBefore,
+		NotAfter:    c.NotAfter,
+		KeyType:     certKeyType(c),
+		SelfSigned:  isSelfSigned(c),
+		Expired:     now.After(c.NotAfter),
+		ExpiresSoon: !now.After(c.NotAfter) && c.NotAfter.Sub(now) < certExpiryWarning,
+	}
+	cert.SANs = append(cert.SANs, c.DNSNames...)
+	for _, ip := range c.IPAddresses {
+		cert.SANs = append(cert.SANs, ip.String())
+	}
+	return cert
+}
+
+// isSelfSigned emisor igual al sujeto y, si viene, la misma clave de autoridad.
+// No se verifica la firma: muchos equipos todavía firman con SHA-1, que Go rechaza.
+func isSelfSigned(c *x509.Certificate) bool {
+	return bytes.Equal(c.RawSubject, c.RawIssuer) &&
+		(len(c.AuthorityKeyId) == 0 || bytes.Equal(c.AuthorityKeyId, c.SubjectKeyId))
+}
+
+// certKeyType "RSA 2048", "ECDSA P-256", "Ed25519"
+func certKeyType(c *x509.Certificate) string {
+	switch k := c.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + k.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return c.PublicKeyAlgorithm.String()
+}
+
+// tlsNameHint nombre del equipo según el certificado: el CN o el primer SAN DNS,
+// salvo comodines, IPs y nombres genéricos como "localhost"
+func tlsNameHint(certs []models.TLSCert) string {
+	for _, c := range certs {
+		for _, name := range append([]string{c.Subject}, c.SANs...) {
+			name = strings.TrimSpace(name)
+			if name == "" || strings.HasPrefix(name, "*") || strings.EqualFold(name, "localhost") ||
+				strings.HasPrefix(strings.ToLower(name), "localhost.") {
+				continue
+			}
+			if _, err := netip.ParseAddr(name); err == nil {
+				continue
+			}
+			return name
+		}
+	}
+	return ""
+}
@@ -1,22 +1,103 @@
 package wsclient
 
 import (
+	"context"
 	"encoding/json"
 	"escaner/internal/backend"
+	"escaner/internal/history"
 	"escaner/internal/models"
 	scan "escaner/internal/utils"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 )
 
 // Estructura del mensaje WS esperado
 type ScanRequest struct {
-	Subred string `json:"subnet"`
+	Subred   string `json:"subnet"`
+	Profile  string `json:"profile"`  // perfil de escaneo (por defecto scan.DefaultProfile)
+	AllPorts bool   `json:"allPorts"` // reportar todos los puertos abiertos de cada host
+	Services bool   `json:"services"` // identificar servicio/versión por puerto
+	// límites de cortesía en sondas por segundo (0 = sin límite, o el de fallback)
+	Rate       int `json:"rate"`
+	SubnetRate int `json:"subnetRate"`
+	// comunidades y usuarios v3 para SNMP; se suman a las del perfil (y activan
+	// SNMP aunque el perfil no lo tenga)
+	SNMP *scan.SNMPCredentials `json:"snmp"`
 }
 
-// Función que ejecuta el escaneo cuando llega por WS
-func RunScanFromWS(data interface{}, backendURL string, backendTimeoutSec int) {
+// History historial donde se guardan los escaneos pedidos por WS para reportar
+// los cambios respecto del anterior de la misma subred (nil = no se guardan)
+var History *history.Store
+
+// límites que se aplican siempre cuando el agente se registró como fallback,
+// aunque el backend pida más o no pida ninguno
+const (
+	fallbackRate       = 100
+	fallbackSubnetRate = 25
+)
+
+// capRate limita requested a limit; requested <= 0 (sin límite) también queda en limit
+func capRate(requested, limit int) int {
+	if requested <= 0 || requested > limit {
+		return limit
+	}
+	return requested
+}
+
+// scanJob guarda el escaneo WS en curso para poder cancelarlo con "scan_cancel".
+// Solo se permite un escaneo a la vez por agente.
+type scanJob struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// start lanza run en segundo plano con un contexto hijo de parent.
+// Devuelve false si ya hay un escaneo corriendo.
+func (j *scanJob) start(parent context.Context, run func(ctx context.Context)) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.cancel != nil {
+		return false
+	}
+	ctx, cancel := context.WithCancel(parent)
+	done := make(chan struct{})
+	j.cancel = cancel
+	j.done = done
+
+	go func() {
+		defer close(done)
+		defer func() {
+			j.mu.Lock()
+			j.cancel = nil
+			j.done = nil
+			j.mu.Unlock()
+			cancel()
+		}()
+		run(ctx)
+	}()
+	return true
+}
+
+// stop cancela el escaneo en curso (si hay) y devuelve un canal que se cierra
+// cuando el escaneo terminó de limpiar. Devuelve nil si no había escaneo.
+func (j *scanJob) stop() <-chan struct{} {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.cancel == nil {
+		return nil
+	}
+	j.cancel()
+	return j.done
+}
+
+// Función que ejecuta el escaneo cuando llega por WS.
+// Si ctx se cancela el escaneo se aborta y se envía al backend el mensaje final "cancelled".
+// Con isFallback los límites de velocidad no superan fallbackRate/fallbackSubnetRate.
+// Con History los escaneos completos se guardan y sus cambios van a changesURL.
+func RunScanFromWS(ctx context.Context, data interface{}, backendURL, changesURL string, backendTimeoutSec int, isFallback bool) {
 	bytes, _ := json.Marshal(data)
 	var req ScanRequest
 	if err := json.Unmarshal(bytes, &req); err != nil {
@@ -33,14 +114,24 @@ func RunScanFromWS(data interface{}, backendURL string, backendTimeoutSec int) {
 	ipRange := fmt.Sprintf("192.168.%d.1-255", subredInt)
 	fmt.Printf("🚀 Escaneo iniciado desde WS: %s\n", ipRange)
 
-	ips, err := scan.ExpandArgToIPs(ipRange)
+	targets, err := scan.ParseTargets([]string{ipRange}, nil)
 	if err != nil {
 		fmt.Println("❌ Error generando IPs:", err)
 		return
 	}
 
-	ports := scan.ParsePorts("22,80,443,3389,445,139,9100,631,515,3306,53,8080,137,161")
-	timeout := 1 * time.Second
+	if req.Profile == "" {
+		req.Profile = scan.DefaultProfile
+	}
+	profile, err := scan.LookupProfile(req.Profile)
+	if err != nil {
+		fmt.Println("❌", err)
+		// el backend espera el mensaje final aunque el escaneo no haya empezado
+		if err := backend.SendFinalMessage(time.Duration(backendTimeoutSec)*time.Second, backendURL, req.Subred, "error", err.Error()); err != nil {
+			fmt.Println("❌ Error enviando mensaje final:", err)
+		}
+		return
+	}
 
 	onAlive := func(r models.Result) {
 		fmt.Println("📡 Dispositivo detectado:", scan.FormatResult(r))
@@ -50,12 +141,60 @@ func RunScanFromWS(data interface{}, backendURL string, backendTimeoutSec int) {
 		}
 	}
 
-	scan.ScanIPs(ips, ports, timeout, 200, onAlive)
-	fmt.Println("✅ Escaneo WS completado.")
+	// el pedido solo puede sumar a lo que trae el perfil
+	opts := profile.Options()
+	opts.AllPorts = opts.AllPorts || req.AllPorts
+	opts.ServiceDetection = opts.ServiceDetection || req.Services
+	if req.Rate > 0 {
+		opts.Rate = req.Rate
+	}
+	if req.SubnetRate > 0 {
+		opts.SubnetRate = req.SubnetRate
+	}
+	if req.SNMP != nil {
+		opts.SNMP = opts.SNMP.Merge(req.SNMP)
+	}
+	if isFallback {
+		opts.Rate = capRate(opts.Rate, fallbackRate)
+		opts.SubnetRate = capRate(opts.SubnetRate, fallbackSubnetRate)
+	}
+	started := time.Now()
+	results, _ := scan.ScanIPs(ctx, targets.All(), opts, onAlive)
+
+	status := "ok"
+	if ctx.Err() != nil {
+		status = "cancelled"
+		fmt.Println("🛑 Escaneo WS cancelado.")
+	} else {
+		fmt.Println("✅ Escaneo WS completado.")
+		recordHistory(ipRange, started, profile.Name, opts, results, changesURL, backendTimeoutSec)
+	}
 
 	// 🚀 Enviar mensaje final al backend
-	err = backend.SendFinalMessage(time.Duration(backendTimeoutSec)*time.Second, backendURL, req.Subred)
+	err = backend.SendFinalMessage(time.Duration(backendTimeoutSec)*time.Second, backendURL, req.Subred, status, "")
 	if err != nil {
 		fmt.Println("❌ Error enviando mensaje final:", err)
 	}
 }
+
+// recordHistory guarda el escaneo en History y envía al backend los cambios
+// respecto del escaneo anterior de la misma subred
+func recordHistory(ipRange string, started time.Time, profile string, opts scan.ScanOptions, results []models.Result, changesURL string, backendTimeoutSec int) {
+	if History == nil {
+		return
+	}
+	run := history.NewRun(GetMacAddress(), []string{ipRange}, nil, started, results)
+	run.Profile = profile
+	run.PortsEnumerated = opts.AllPorts || opts.ServiceDetection
+	changes, prevID, err := History.Record(run)
+	if err != nil {
+		fmt.Println("❌ Error en el historial:", err)
+	}
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Printf("🔀 Cambios respecto del escaneo %s: %d\n", prevID, len(changes))
+	if err := backend.SendChanges(run, prevID, changes, time.Duration(backendTimeoutSec)*time.Second, changesURL); err != nil {
+		fmt.Println("❌ Error enviando cambios al backend:", err)
+	}
+}
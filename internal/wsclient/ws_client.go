@@ -1,7 +1,9 @@
 package wsclient
 
 import (
+	"context"
 	"encoding/json"
+	scan "escaner/internal/utils"
 	"fmt"
 	"log"
 	"net/url"
@@ -30,7 +32,12 @@ func ConnectWebSocket(serverAddr string, ip string, isFallback bool) {
 	defer c.Close()
 
 	done := make(chan struct{})
+	// ctx se cancela al desconectar o con Ctrl+C y aborta cualquier escaneo en curso
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var job scanJob
 	endpoint := fmt.Sprintf("http://%s:3000/dispositivos/found", ip)
+	changesEndpoint := fmt.Sprintf("http://%s:3000/dispositivos/changes", ip)
 
 	// 🧠 Construir el mensaje inicial con datos del sistema
 	registerMsg := WSMessage{
@@ -60,16 +67,39 @@ func ConnectWebSocket(serverAddr string, ip string, isFallback bool) {
 			fmt.Printf("📩 Mensaje recibido del servidor: %s\n", message)
 
 			// Aquí puedes interpretar comandos que el backend envía
-			// Por ejemplo: {"type": "scan", "data": {"subred": "182"}}
+			// Por ejemplo: {"type": "scan_request", "data": {"subnet": "182"}}
+			//              {"type": "scan_cancel"}
+			//              {"type": "device_rules", "data": {"rules": [...]}}
 			var msg WSMessage
 			if err := json.Unmarshal(message, &msg); err == nil {
-				if msg.Type == "scan_request" {
+				switch msg.Type {
+				case "scan_request":
 					fmt.Println("🚀 Iniciando escaneo solicitado por WS con data:", msg.Data)
-					RunScanFromWS(msg.Data, endpoint, 3)
+					data := msg.Data
+					// el escaneo corre aparte para seguir leyendo mensajes (ej. scan_cancel)
+					started := job.start(ctx, func(jobCtx context.Context) {
+						RunScanFromWS(jobCtx, data, endpoint, changesEndpoint, 3, isFallback)
+					})
+					if !started {
+						fmt.Println("⚠️ Ya hay un escaneo en curso, se ignora la solicitud")
+					}
 					//RunScanFromWS(msg.Data, "http://ip:3000/dispositivos/found", 3)
 					//RunScanFromWS(msg.Data, "http://192.168.0.24:3000/dispositivos/found", 3)
+				case "scan_cancel":
+					if job.stop() == nil {
+						fmt.Println("⚠️ scan_cancel recibido pero no hay escaneo en curso")
+					} else {
+						fmt.Println("🛑 Cancelando escaneo por solicitud WS")
+					}
+				case "device_rules":
+					// reglas de clasificación enviadas por el backend (mismo formato que device_rules.json)
+					raw, _ := json.Marshal(msg.Data)
+					if err := scan.LoadDeviceRulesJSON(raw); err != nil {
+						log.Println("Reglas de dispositivos inválidas:", err)
+					} else {
+						fmt.Println("📋 Reglas de dispositivos actualizadas por WS")
+					}
 				}
-
 			}
 		}
 	}()
@@ -92,6 +122,11 @@ func ConnectWebSocket(serverAddr string, ip string, isFallback bool) {
 		case <-interrupt:
 			log.Println("🔌 Cierre solicitado, desconectando WS...")
 
+			// 🛑 Abortar el escaneo en curso y esperar su mensaje final al backend
+			if jobDone := job.stop(); jobDone != nil {
+				<-jobDone
+			}
+
 			// 🔒 Envía el mensaje de cierre al servidor
 			err := c.WriteMessage(websocket.CloseMessage,
 				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))